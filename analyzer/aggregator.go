@@ -0,0 +1,150 @@
+// analyzer/aggregator.go
+// This file contains the aggregator counting window entries by a dimension, such as level, IP or a field.
+
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"log_analyzer/models"
+)
+
+// Built-in dimensions entries can be grouped by, besides the named groups
+// of a custom line format
+const (
+	GroupLevel     = "level"
+	GroupIP        = "ip"
+	GroupErrorType = "error_type"
+)
+
+// Selector returns the value an entry is counted under, or "" to leave the
+// entry out
+type Selector func(entry models.LogEntry) string
+
+// SelectLevel counts entries by level
+func SelectLevel(entry models.LogEntry) string {
+	return entry.Level
+}
+
+// SelectIP counts entries by source IP
+func SelectIP(entry models.LogEntry) string {
+	return entry.IP
+}
+
+// SelectErrorType counts ERROR entries by error type
+func SelectErrorType(entry models.LogEntry) string {
+	if entry.Level != "ERROR" {
+		return ""
+	}
+	return entry.ErrorType
+}
+
+// SelectField counts entries by a field extracted by a named group of the
+// line format, see models.LogEntry.Fields
+func SelectField(name string) Selector {
+	return func(entry models.LogEntry) string {
+		return entry.Fields[name]
+	}
+}
+
+// SelectorFor returns the selector of a group-by dimension: one of the
+// Group* names, or else the name of an entry field
+func SelectorFor(dimension string) (Selector, error) {
+	switch dimension {
+	case "":
+		return nil, fmt.Errorf("no dimension given")
+	case "timestamp", "message":
+		return nil, fmt.Errorf("cannot group by %s, every entry has its own", dimension)
+	case GroupLevel:
+		return SelectLevel, nil
+	case GroupIP:
+		return SelectIP, nil
+	case GroupErrorType:
+		return SelectErrorType, nil
+	default:
+		return SelectField(dimension), nil
+	}
+}
+
+// Aggregator counts entries by the value a selector picks from them, as
+// entries enter and leave a window, and ranks the values. It is not safe
+// for concurrent use; the sliding window guards its aggregators with its
+// own lock.
+type Aggregator struct {
+	selector  Selector
+	counts    map[string]int
+	keepEmpty bool // Keep values whose count dropped to 0, instead of forgetting them
+}
+
+// NewAggregator creates an aggregator counting entries by selector. Values
+// are forgotten once no entry in the window has them, so memory is bounded
+// by the window.
+func NewAggregator(selector Selector) *Aggregator {
+	return &Aggregator{
+		selector: selector,
+		counts:   make(map[string]int),
+	}
+}
+
+// Add counts an entry entering the window
+func (g *Aggregator) Add(entry models.LogEntry) {
+	if value := g.selector(entry); value != "" {
+		g.counts[value]++
+	}
+}
+
+// Remove uncounts an entry leaving the window
+func (g *Aggregator) Remove(entry models.LogEntry) {
+	value := g.selector(entry)
+	if value == "" {
+		return
+	}
+
+	g.counts[value]--
+	if g.counts[value] <= 0 && !g.keepEmpty {
+		delete(g.counts, value)
+	}
+}
+
+// Count returns the number of entries with value
+func (g *Aggregator) Count(value string) int {
+	return g.counts[value]
+}
+
+// Len returns the number of distinct values counted
+func (g *Aggregator) Len() int {
+	return len(g.counts)
+}
+
+// Counts returns a copy of the count of every value
+func (g *Aggregator) Counts() map[string]int {
+	counts := make(map[string]int, len(g.counts))
+	for value, count := range g.counts {
+		counts[value] = count
+	}
+	return counts
+}
+
+// Top returns the n values with the most entries, breaking ties by value
+// for a stable order
+func (g *Aggregator) Top(n int) []models.FieldCount {
+	result := make([]models.FieldCount, 0, len(g.counts))
+	for value, count := range g.counts {
+		if count > 0 {
+			result = append(result, models.FieldCount{Value: value, Count: count})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+
+	if n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
@@ -1,21 +1,61 @@
 // analyzer/analyzer.go
 // Package analyzer provides a log analyzer that processes log entries and generates statistics
-// It includes a deliberate concurrency bug that causes underreporting of error counts
-// when processing high volumes of ERROR logs
 
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"log_analyzer/models"
+	"log_analyzer/queue"
 )
 
-// RateBucket tracks entries per second
+// topIPCount is how many of the busiest source IPs are reported in stats
+const topIPCount = 10
+
+// topGroupCount is how many of the most frequent group-by values are
+// reported in stats
+const topGroupCount = 10
+
+// topErrorCount is how many of the top weighted error types are reported
+const topErrorCount = 10
+
+// Default alerting and window-adjustment thresholds
+const (
+	DefaultErrorAlertThreshold = 5.0  // errors/sec
+	DefaultRateHigh            = 2500 // entries/sec
+	DefaultRateLow             = 600  // entries/sec
+	DefaultRateAlpha           = 0.3  // weight of the newest rate in the smoothed rate
+	DefaultResizeTicks         = 3    // consecutive stats ticks past a threshold before resizing
+	DefaultAlertCooldown       = 30 * time.Second
+)
+
+// Alert categories, each throttled by its own cooldown
+const (
+	alertBufferResize  = "buffer-resize"
+	alertBufferShrink  = "buffer-shrink"
+	alertWindowAdjust  = "window-adjust"
+	alertHighErrorRate = "high-error-rate"
+	alertIPSpike       = "ip-spike:" // Followed by the IP, so each IP is throttled separately
+	alertRateAnomaly   = "rate-anomaly"
+	alertSLOBurn       = "slo-burn"
+	alertClockSkew     = "clock-skew"
+	alertLatency       = "latency"
+	alertParseFailure  = "parse-failure"
+	alertStateSave     = "state-save"
+)
+
+// minIPSpikeErrors is the fewest recent errors an IP needs before it can be
+// flagged, so a couple of errors from a quiet client are not reported
+const minIPSpikeErrors = 10
+
+// RateBucket counts the entries of one rate bucket interval, see SetRateBucket
 type RateBucket struct {
 	Count     int
 	Timestamp time.Time
@@ -23,46 +63,138 @@ type RateBucket struct {
 
 // Analyzer processes log entries and generates statistics
 type Analyzer struct {
+	clock           Clock // Source of the current time, see SetClock
 	window          *SlidingWindow
 	patternTracker  *PatternTracker
-	logChan         chan models.LogEntry
+	entries         *queue.Queue // Entries waiting to be processed, grown under bursts (see buffer.go)
 	statsChan       chan *models.LogStats
 	alertChan       chan models.Alert
 	stopChan        chan struct{}
+	stopOnce        sync.Once
 	stats           *models.LogStats
 	rateBuckets     []*RateBucket
+	rateTiers       []*rateTier   // Coarser buckets for history beyond rateBuckets
+	baseline        *rateBaseline // Learned per-bucket rate, see anomaly.go
+	anomalySigma    float64       // Deviation that raises a rate anomaly alert (0 disables)
 	mux             sync.Mutex
 	debugMode       bool
 	debugLogger     *log.Logger
 	skippedEntries  int
+	rejectCounts    map[string]int // Skipped entries by reject reason
+	filteredEntries int
+	totalLines      int  // Every entry received, valid or not
+	filteredInRate  bool // Count filtered entries in the processing rate
 	bufferResized   bool
-	bufferSize      int
-	
-	// For the deliberate concurrency bug
-	buggyConcurrency bool
+	bufferSize      int // Capacity of entries, guarded by mux for stats
+
+	// Growth of entries under bursts, see SetBufferGrowth
+	initialBufferSize int
+	bufferGrowAt      float64
+	bufferGrowFactor  float64
+
+	// Shrinking of a grown buffer, see SetBufferShrink. The stats goroutine
+	// requests a size in shrinkTo, the processing goroutine applies it.
+	bufferShrinkBelow float64
+	bufferLowTicks    int       // Guarded by mux
+	lastBufferResize  time.Time // Guarded by mux
+	shrinkTo          atomic.Int64
+	processDone       chan struct{}       // Closed when processLogs returns
+	rateCSV           *rateCSV            // Optional export of finalized rate buckets
+	gaps              *gapHistogram       // Inter-arrival times of valid entries, guarded by mux
+	lifetime          *lifetimeAggregator // Totals since start, guarded by mux
+	slo               *sloTracker         // Error budget burn, guarded by mux
+	staleInput        staleTracker        // Silence of the input, guarded by mux
+	skew              skewTracker         // Log timestamps against the wall clock, guarded by mux
+	latency           latencyAlert        // p99 latency above its threshold, guarded by mux
+	parse             parseAlert          // Parse success rate over the latest period, guarded by mux
+	rotateEvery       time.Duration       // Interval the cumulative stats are reset at, see SetStatsRotation
+	rotatedAt         time.Time           // Boundary of the current rotation period, guarded by mux
+	stateFile         string              // Checkpoint of the cumulative stats, see SetStateFile
+	stateInterval     time.Duration       // Interval the state file is written at, 0 only on shutdown
+
+	// Current rate bucket, owned by the goroutine processing entries
+	bucketInterval time.Duration // Length of every rate bucket, see SetRateBucket
+	bucketStart    time.Time
+	bucketCount    int
+
+	// Share of recent errors a single IP must exceed to be flagged (0 disables)
+	ipErrorShareThreshold float64
+
+	// Alerting and window-adjustment thresholds, see SetThresholds
+	errorAlertThreshold float64 // errors/sec
+	rateHigh            float64 // entries/sec above which the window shrinks
+	rateLow             float64 // entries/sec below which the window grows
+
+	// Exponentially weighted moving average of the rate, see SetRateSmoothing
+	rateAlpha      float64
+	smoothedRate   float64
+	rateSmoothed   bool // Set once smoothedRate holds a first sample
+	adjustSmoothed bool // Drive window adjustment from the smoothed rate
+
+	// Hysteresis for window adjustment: consecutive stats ticks the rate has
+	// been above rateHigh or below rateLow, and how many are required
+	highTicks   int
+	lowTicks    int
+	resizeTicks int
+
+	// Per-category alert cooldown, see sendAlert
+	alertCooldown   time.Duration
+	alertMux        sync.Mutex
+	lastAlerts      map[string]time.Time // When each category last fired
+	suppressedCount map[string]int       // Alerts dropped per category since then
 }
 
-// NewAnalyzer creates a new Analyzer
+// NewAnalyzer creates a new Analyzer taking entries off the given queue,
+// whose capacity is the initial buffer size
 func NewAnalyzer(
-	logChan chan models.LogEntry,
+	entries *queue.Queue,
 	statsChan chan *models.LogStats,
 	alertChan chan models.Alert,
 	debugMode bool,
-	initialBufferSize int,
 ) *Analyzer {
+	initialBufferSize := entries.Cap()
 	a := &Analyzer{
-		window:         NewSlidingWindow(60), // Start with 60-second window
-		logChan:        logChan,
-		statsChan:      statsChan,
-		alertChan:      alertChan,
-		stopChan:       make(chan struct{}),
-		stats:          models.NewLogStats(),
-		rateBuckets:    make([]*RateBucket, 0, 120), // Track up to 120 seconds
-		debugMode:      debugMode,
-		bufferSize:     initialBufferSize, // Initial buffer size
-		
-		// Deliberately adding the concurrency bug flag
-		buggyConcurrency: true,
+		clock:        RealClock,
+		window:       NewSlidingWindow(60), // Start with 60-second window
+		entries:      entries,
+		statsChan:    statsChan,
+		alertChan:    alertChan,
+		stopChan:     make(chan struct{}),
+		processDone:  make(chan struct{}),
+		stats:        models.NewLogStats(),
+		rejectCounts: make(map[string]int),
+		rateBuckets:  make([]*RateBucket, 0, 120), // Track up to 120 seconds
+		rateTiers:    newRateTiers(),
+		baseline:     newRateBaseline(DefaultRateBucket),
+		anomalySigma: DefaultAnomalySigma,
+		gaps:         newGapHistogram(),
+		lifetime:     newLifetimeAggregator(RealClock.Now(), DefaultRateBucket),
+		slo:          &sloTracker{},
+		staleInput:   staleTracker{after: DefaultStaleAfter, lastEntry: RealClock.Now()},
+		skew:         skewTracker{threshold: DefaultSkewThreshold},
+		latency:      latencyAlert{ticks: DefaultLatencyAlertTicks},
+		parse:        parseAlert{threshold: DefaultParseAlertThreshold, window: DefaultParseAlertWindow},
+		debugMode:    debugMode,
+		bufferSize:   initialBufferSize, // Initial buffer size
+
+		initialBufferSize: initialBufferSize,
+		bufferGrowAt:      DefaultBufferGrowAt,
+		bufferGrowFactor:  DefaultBufferGrowFactor,
+		bufferShrinkBelow: DefaultBufferShrinkBelow,
+
+		bucketInterval: DefaultRateBucket,
+		bucketStart:    RealClock.Now().Truncate(DefaultRateBucket),
+
+		ipErrorShareThreshold: 0.5,
+		errorAlertThreshold:   DefaultErrorAlertThreshold,
+		rateHigh:              DefaultRateHigh,
+		rateLow:               DefaultRateLow,
+		rateAlpha:             DefaultRateAlpha,
+		filteredInRate:        true,
+		resizeTicks:           DefaultResizeTicks,
+		alertCooldown:         DefaultAlertCooldown,
+		lastAlerts:            make(map[string]time.Time),
+		suppressedCount:       make(map[string]int),
 	}
 
 	a.window.SetAnalyzer(a)
@@ -80,80 +212,282 @@ func NewAnalyzer(
 	return a
 }
 
+// SetClock replaces the clock the analyzer, its sliding window and its
+// pattern tracker read the current time from, e.g. with a fake clock in
+// tests. Call it before Start. Tickers still run on real time.
+func (a *Analyzer) SetClock(clock Clock) {
+	a.clock = clock
+	a.window.SetClock(clock)
+	a.patternTracker.SetClock(clock)
+
+	now := clock.Now()
+	a.bucketStart = now.Truncate(a.bucketInterval)
+	a.lifetime = newLifetimeAggregator(now, a.bucketInterval)
+	a.staleInput.lastEntry = now
+}
+
+// SetTimeReference sets whether the sliding window, and so error rates and
+// emerging patterns, is measured back from the wall clock (TimeWall) or
+// from the newest entry's own timestamp (TimeLog). TimeLog suits historical
+// logs read from a file, whose entries would otherwise all have expired.
+func (a *Analyzer) SetTimeReference(ref TimeReference) {
+	a.window.SetTimeReference(ref)
+	a.skew.logTime = ref == TimeLog
+}
+
+// SetIPErrorShareThreshold sets the fraction (0-1) of recent ERROR entries a
+// single IP must account for, after a sudden increase, to raise an alert.
+// A threshold of 0 disables per-IP error alerts.
+func (a *Analyzer) SetIPErrorShareThreshold(threshold float64) {
+	a.ipErrorShareThreshold = threshold
+}
+
+// SetCompactWindow makes the sliding window drop the raw line and message
+// of stored entries, which dominate its memory use at high rates
+func (a *Analyzer) SetCompactWindow(compact bool) {
+	a.window.SetCompact(compact)
+}
+
+// SetGroupBy reports the most frequent values in the window of a
+// dimension, like the busiest source IPs: one of the Group* names or an
+// entry field, e.g. "status" extracted by a (?P<status>...) group of the
+// line format. An empty dimension disables it.
+func (a *Analyzer) SetGroupBy(dimension string) error {
+	if err := a.window.SetGroupBy(dimension); err != nil {
+		return err
+	}
+
+	a.stats.GroupBy = dimension
+	return nil
+}
+
+// SetThresholds sets the total error rate (errors/sec) that raises a high
+// error rate alert, and the processing rates (entries/sec) above which the
+// window shrinks and below which it grows. rateLow must be below rateHigh.
+func (a *Analyzer) SetThresholds(errorAlert, rateHigh, rateLow float64) error {
+	if rateLow >= rateHigh {
+		return fmt.Errorf("low rate threshold (%.0f) must be below high rate threshold (%.0f)", rateLow, rateHigh)
+	}
+
+	a.errorAlertThreshold = errorAlert
+	a.rateHigh = rateHigh
+	a.rateLow = rateLow
+	return nil
+}
+
+// SetPatternConfig tunes emerging-pattern detection and weighting
+func (a *Analyzer) SetPatternConfig(config PatternConfig) error {
+	return a.patternTracker.SetConfig(config)
+}
+
+// SetRateSmoothing sets the alpha (0-1] of the exponentially weighted
+// moving average rate; higher values follow the raw rate more closely. With
+// adjustWindow set, window resizing uses the smoothed rate instead of the raw
+// one, so short spikes do not make the window thrash.
+func (a *Analyzer) SetRateSmoothing(alpha float64, adjustWindow bool) error {
+	if alpha <= 0 || alpha > 1 {
+		return fmt.Errorf("alpha %.2f must be above 0 and at most 1", alpha)
+	}
+
+	a.rateAlpha = alpha
+	a.adjustSmoothed = adjustWindow
+	return nil
+}
+
+// SetResizeTicks sets how many consecutive stats ticks (one per second) the
+// rate must stay past a threshold before the window is resized, so noisy
+// traffic near a threshold does not make the window flap
+func (a *Analyzer) SetResizeTicks(ticks int) error {
+	if ticks < 1 {
+		return fmt.Errorf("resize ticks must be at least 1, got %d", ticks)
+	}
+
+	a.resizeTicks = ticks
+	return nil
+}
+
+// SetAlertCooldown sets the minimum interval between two alerts of the same
+// category, one of the alert* constants: buffer resize and shrink, window
+// adjustment, high error rate, error spike (per IP), rate anomaly, SLO
+// burn, clock skew, latency, parse failure and state save. Repeats within
+// the interval are dropped and summarized on the next alert. Critical
+// entry, stale input and stats rotation alerts are never throttled. 0
+// disables the cooldown.
+func (a *Analyzer) SetAlertCooldown(cooldown time.Duration) {
+	a.alertCooldown = cooldown
+}
+
+// SetFilteredInRate sets whether filtered entries still count
+// towards the processing rate, which then reflects the true input rate
+func (a *Analyzer) SetFilteredInRate(include bool) {
+	a.filteredInRate = include
+}
+
+// SetRateCSV appends a timestamp,count row to the CSV file at path for
+// every rate bucket (per second by default) as it is finalized
+func (a *Analyzer) SetRateCSV(path string) error {
+	csv, err := openRateCSV(path)
+	if err != nil {
+		return err
+	}
+
+	a.rateCSV = csv
+	return nil
+}
+
 // Start begins analyzing logs
 func (a *Analyzer) Start() {
 	go a.processLogs()
 	go a.updateStats()
 }
 
-// Stop signals the analyzer to stop
+// StartContext begins analyzing logs like Start, and stops the analyzer
+// once ctx is cancelled. Buffered entries are left for StopAndDrain.
+func (a *Analyzer) StartContext(ctx context.Context) {
+	a.Start()
+	go func() {
+		select {
+		case <-ctx.Done():
+			a.Stop()
+		case <-a.stopChan:
+		}
+	}()
+}
+
+// Stop signals the analyzer to stop. It may be called more than once.
 func (a *Analyzer) Stop() {
-	close(a.stopChan)
+	a.stopOnce.Do(func() { close(a.stopChan) })
 }
 
+// StopAndDrain stops the analyzer after processing the entries still
+// waiting in the queue, then returns a final stats snapshot for the caller
+// to render, writing the state file a last time if one is set. Draining
+// ends as soon as the queue is empty or the timeout expires, so a reader
+// that keeps pushing cannot block shutdown.
+func (a *Analyzer) StopAndDrain(timeout time.Duration) *models.LogStats {
+	a.Stop()
+	<-a.processDone
+
+	drained := 0
+	deadline := time.After(timeout)
+drain:
+	for {
+		select {
+		case <-deadline:
+			break drain
+		default:
+		}
+		entry, ok := a.entries.TryPop()
+		if !ok {
+			break
+		}
+		a.processEntry(entry)
+		drained++
+	}
+
+	// Count the partial bucket so the final rate includes the drained tail
+	a.updateRateBucket(a.bucketStart, a.bucketCount)
+	a.bucketCount = 0
+
+	if a.rateCSV != nil {
+		if err := a.rateCSV.close(); err != nil && a.debugMode {
+			a.debugLogger.Printf("Failed to close rate CSV: %v", err)
+		}
+	}
+
+	if a.debugMode {
+		a.debugLogger.Printf("Drained %d buffered entries on shutdown", drained)
+	}
+
+	stats := a.generateStats()
+	a.saveState()
+	return stats
+}
+
+// processLogs takes entries off the queue and processes them in order.
+// Bursts pile up in the queue, which grows to absorb them.
 func (a *Analyzer) processLogs() {
-	secondBucket := time.Now().Truncate(time.Second)
-	secondCount := 0
+	defer close(a.processDone)
 
+	popped := 0
 	for {
 		select {
 		case <-a.stopChan:
 			return
-		case entry := <-a.logChan:
-			now := time.Now()
-
-			// Check if we need to update rate bucket
-			if now.Truncate(time.Second) != secondBucket {
-				a.updateRateBucket(secondBucket, secondCount)
-				secondBucket = now.Truncate(time.Second)
-				secondCount = 0
-			}
-			secondCount++
-
-			if !entry.IsValid {
-				a.mux.Lock()
-				a.skippedEntries++
-				a.mux.Unlock()
-				continue
-			}
+		default:
+		}
 
-			// Process the entry
-			a.window.Add(entry)
-			a.patternTracker.UpdatePattern(entry)
-
-			// Here's where we implement the deliberate concurrency bug
-            // The bug will cause error counts to be underreported when processing
-            // high volumes of ERROR logs
-            if entry.Level == "ERROR" && a.buggyConcurrency {
-                // Bug: No lock protection when processing ERROR logs at high rates
-                // This will cause race conditions when error rate is high
-                a.stats.EntriesProcessed++ // Missing lock, only triggered for ERROR logs
-            } else {
-                a.mux.Lock()
-                a.stats.EntriesProcessed++
-                a.mux.Unlock()
-            }
-
-			// Check for buffer resize need
-			if secondCount > int(float64(a.bufferSize) * 0.8) {
-				newSize := int(float64(a.bufferSize) * 1.5)
-				a.mux.Lock()
-				a.bufferSize = newSize
-				a.bufferResized = true
-				a.mux.Unlock()
-				
-				// Send alert about buffer resize
-				a.alertChan <- models.Alert{
-					Timestamp: now,
-					Message:   fmt.Sprintf("⚠️ Burst detected: %d entries in 1 sec, resized buffer to %d", secondCount, newSize),
-				}
-				
-				if a.debugMode {
-					a.debugLogger.Printf("Resized buffer to %d due to high load", newSize)
-				}
+		a.shrinkBuffer()
+		if popped%bufferCheckEvery == 0 {
+			a.growBuffer()
+		}
+		entry, ok := a.entries.TryPop()
+		if !ok {
+			select {
+			case <-a.stopChan:
+				return
+			case <-a.entries.Ready():
 			}
+			continue
 		}
+		popped++
+		a.processEntry(entry)
+	}
+}
+
+// processEntry updates the rate buckets, window and counters for one entry.
+// It must only be called from the goroutine consuming the queue.
+func (a *Analyzer) processEntry(entry models.LogEntry) {
+	now := a.clock.Now()
+
+	// Check if we need to update rate bucket
+	if now.Truncate(a.bucketInterval) != a.bucketStart {
+		a.updateRateBucket(a.bucketStart, a.bucketCount)
+		a.bucketStart = now.Truncate(a.bucketInterval)
+		a.bucketCount = 0
+	}
+	if !entry.Filtered || a.filteredInRate {
+		a.bucketCount++
+	}
+
+	a.mux.Lock()
+	a.totalLines++
+	if entry.IsValid {
+		a.staleInput.lastEntry = now
+	}
+	a.mux.Unlock()
+
+	if entry.Filtered {
+		a.mux.Lock()
+		a.filteredEntries++
+		a.mux.Unlock()
+		return
+	}
+
+	if !entry.IsValid {
+		a.mux.Lock()
+		a.skippedEntries++
+		a.rejectCounts[entry.Reject]++
+		a.mux.Unlock()
+		return
+	}
+
+	// Critical entries are reported immediately, whatever the rate
+	if models.LevelSeverity(entry.Level) == models.SeverityCritical {
+		a.alertFatal(entry, now)
 	}
+
+	// Process the entry
+	a.window.Add(entry)
+	a.patternTracker.UpdatePattern(entry)
+
+	a.mux.Lock()
+	a.stats.EntriesProcessed++
+	a.gaps.observe(now, time.Duration(a.stats.WindowSize)*time.Second)
+	a.lifetime.observe(entry)
+	a.skew.observe(now, entry.Timestamp)
+	a.slo.observe(now, burnsBudget(entry.Level))
+	a.mux.Unlock()
 }
 
 func (a *Analyzer) updateRateBucket(timestamp time.Time, count int) {
@@ -166,21 +500,45 @@ func (a *Analyzer) updateRateBucket(timestamp time.Time, count int) {
 		Timestamp: timestamp,
 	})
 
-	// Remove buckets older than 120 seconds (our max window size)
-	cutoff := time.Now().Add(-120 * time.Second)
+	// Export the bucket before pruning, so the file keeps the full history
+	if a.rateCSV != nil {
+		if err := a.rateCSV.write(timestamp, count); err != nil && a.debugMode {
+			a.debugLogger.Printf("Failed to write rate CSV: %v", err)
+		}
+	}
+
+	a.lifetime.addRate(timestamp, count)
+	a.checkRateAnomaly(timestamp, count)
+
+	// Move buckets older than 120 seconds (our max window size) into the
+	// coarser tiers, so the long-term history is kept at lower resolution
+	now := a.clock.Now()
+	cutoff := now.Add(-120 * time.Second)
 	newBuckets := make([]*RateBucket, 0, len(a.rateBuckets))
+	var expired []*RateBucket
 	for _, bucket := range a.rateBuckets {
 		if bucket.Timestamp.After(cutoff) {
 			newBuckets = append(newBuckets, bucket)
+		} else {
+			expired = append(expired, bucket)
 		}
 	}
 	a.rateBuckets = newBuckets
+	a.foldRateBuckets(expired, now)
 }
 
 func (a *Analyzer) updateStats() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// Checkpoint the state file on its own ticker, if one is set
+	var checkpoint <-chan time.Time
+	if a.stateFile != "" && a.stateInterval > 0 {
+		stateTicker := time.NewTicker(a.stateInterval)
+		defer stateTicker.Stop()
+		checkpoint = stateTicker.C
+	}
+
 	for {
 		select {
 		case <-a.stopChan:
@@ -188,6 +546,8 @@ func (a *Analyzer) updateStats() {
 		case <-ticker.C:
 			stats := a.generateStats()
 			a.statsChan <- stats
+		case <-checkpoint:
+			a.saveState()
 		}
 	}
 }
@@ -196,41 +556,77 @@ func (a *Analyzer) generateStats() *models.LogStats {
 	a.mux.Lock()
 	defer a.mux.Unlock()
 
+	// Start a new period of cumulative stats if a rotation boundary passed
+	a.rotateStats(a.clock.Now())
+
 	// Calculate current processing rate
 	currentRate := a.calculateRate(10) // Last 10 seconds
-	
+
 	// Update peak rate if needed
 	if currentRate > a.stats.PeakRate {
 		a.stats.PeakRate = currentRate
 	}
 
+	// Fold the raw rate into the smoothed rate, seeding it with the first sample
+	if a.rateSmoothed {
+		a.smoothedRate = a.rateAlpha*currentRate + (1-a.rateAlpha)*a.smoothedRate
+	} else {
+		a.smoothedRate = currentRate
+		a.rateSmoothed = true
+	}
+
 	// Adjust window size based on processing rate
+	adjustRate := currentRate
+	if a.adjustSmoothed {
+		adjustRate = a.smoothedRate
+	}
+
+	// Count consecutive ticks past either threshold; any tick in between
+	// the thresholds starts the count over
+	switch {
+	case adjustRate > a.rateHigh:
+		a.highTicks++
+		a.lowTicks = 0
+	case adjustRate < a.rateLow:
+		a.lowTicks++
+		a.highTicks = 0
+	default:
+		a.highTicks = 0
+		a.lowTicks = 0
+	}
+
 	newWindowSize := a.stats.WindowSize
-	if currentRate > 400 && a.stats.WindowSize > 30 {
+	if a.highTicks >= a.resizeTicks && a.stats.WindowSize > 30 {
+		a.highTicks = 0
 		newWindowSize = max(30, a.stats.WindowSize-10)
-		a.alertChan <- models.Alert{
-			Timestamp: time.Now(),
+		a.sendAlert(alertWindowAdjust, models.Alert{
+			Timestamp: a.clock.Now(),
 			Message:   fmt.Sprintf("⚠️ Adjusted window to %d sec due to rate surge", newWindowSize),
-		}
-	} else if currentRate < 600 && a.stats.WindowSize < 120 {
+			Severity:  models.SeverityInfo,
+		})
+	} else if a.lowTicks >= a.resizeTicks && a.stats.WindowSize < 120 {
+		a.lowTicks = 0
 		newWindowSize = min(120, a.stats.WindowSize+10)
 
 		// alert for expansion
-		a.alertChan <- models.Alert{
-			Timestamp: time.Now(),
+		a.sendAlert(alertWindowAdjust, models.Alert{
+			Timestamp: a.clock.Now(),
 			Message:   fmt.Sprintf("⚠️ Adjusted window to %d sec due to lower load", newWindowSize),
-	}
+			Severity:  models.SeverityInfo,
+		})
 	}
 
-	// If window size changed, update it
+	// If window size changed, update it. PreviousWindowSize only differs from
+	// WindowSize in the stats published right after a change, so the display
+	// shows the adjustment for one cycle instead of indefinitely.
+	a.stats.PreviousWindowSize = a.stats.WindowSize
 	if newWindowSize != a.stats.WindowSize {
-		a.stats.PreviousWindowSize = a.stats.WindowSize
 		a.stats.WindowSize = newWindowSize
 		a.window.SetDuration(newWindowSize)
-		
+
 		if a.debugMode {
-			a.debugLogger.Printf("Adjusted window size to %d seconds based on rate: %.2f entries/sec", 
-				newWindowSize, currentRate)
+			a.debugLogger.Printf("Adjusted window size to %d seconds based on rate: %.2f entries/sec",
+				newWindowSize, adjustRate)
 		}
 	}
 
@@ -239,10 +635,67 @@ func (a *Analyzer) generateStats() *models.LogStats {
 
 	// Update stats
 	a.stats.CurrentRate = currentRate
+	a.stats.SmoothedRate = a.smoothedRate
+	a.stats.RateHistory = a.rateHistory(60)
+	a.stats.RateTiers = a.rateTierSeries()
+	a.stats.BaselineRate = 0
+	a.stats.BaselineStdDev = 0
+	if a.baseline.learned() {
+		a.stats.BaselineRate = a.baseline.mean / a.bucketSeconds()
+		a.stats.BaselineStdDev = a.baseline.stddev() / a.bucketSeconds()
+	}
 	a.stats.LevelCounts = levelCounts
 	a.stats.ErrorCounts = errorCounts
-	a.stats.LastUpdated = time.Now()
+	a.stats.LastUpdated = a.clock.Now()
 	a.stats.SkippedEntries = a.skippedEntries
+	a.stats.TotalLines = a.totalLines
+	a.checkBufferShrink(currentRate)
+	a.stats.BufferSize = a.bufferSize
+	a.stats.BufferUsed = a.entries.Len()
+	a.stats.BufferResized = a.bufferSize != a.initialBufferSize
+	a.stats.FilteredEntries = a.filteredEntries
+	a.stats.DroppedEntries = int(a.entries.DroppedEntries())
+	a.stats.RejectCounts = make(map[string]int, len(a.rejectCounts))
+	for reason, count := range a.rejectCounts {
+		a.stats.RejectCounts[reason] = count
+	}
+
+	// Get latency percentiles for entries still inside the window
+	percentiles, samples := a.window.GetDurationPercentiles(50, 95, 99)
+	a.stats.LatencySamples = samples
+	a.stats.LatencyP50 = percentiles[0]
+	a.stats.LatencyP95 = percentiles[1]
+	a.stats.LatencyP99 = percentiles[2]
+
+	// Get the rate of each level, and the warning rate, tracked separately
+	// from errors at a lower priority
+	a.stats.LevelRates = make(map[string]float64, len(levelCounts))
+	a.stats.WarningRate = 0
+	for level := range levelCounts {
+		rate := a.window.GetLevelRate(level, a.stats.WindowSize)
+		a.stats.LevelRates[level] = rate
+		if models.LevelSeverity(level) == models.SeverityWarning {
+			a.stats.WarningRate += rate
+		}
+	}
+
+	// Get the distribution of gaps between entries, to tell bursty from steady load
+	a.stats.InterArrival = a.gaps.buckets()
+
+	// Get the totals since start, which the window does not limit
+	a.stats.Lifetime = a.lifetime.snapshot()
+
+	// Get the error budget against the SLO, if one is set
+	a.stats.SLO = a.sloStats(a.clock.Now())
+
+	// Get the busiest source IPs
+	a.stats.TopIPs = a.window.GetTopIPs(topIPCount)
+	a.stats.UniqueIPs = a.window.GetUniqueIPs()
+
+	// Get the most frequent values of the group-by field
+	if a.stats.GroupBy != "" {
+		a.stats.TopGroups = a.window.GetTopGroups(topGroupCount)
+	}
 
 	// Get error rates
 	a.stats.ErrorRates = make(map[string]float64)
@@ -250,6 +703,14 @@ func (a *Analyzer) generateStats() *models.LogStats {
 		a.stats.ErrorRates[errType] = a.window.GetErrorRate(errType, a.stats.WindowSize)
 	}
 
+	// Get the top errors, weighted by pattern tracking
+	a.stats.TopErrors = a.patternTracker.GetTopErrors(topErrorCount)
+
+	// Forget error types that stopped occurring
+	if evicted := a.patternTracker.PruneIdle(); evicted > 0 && a.debugMode {
+		a.debugLogger.Printf("Evicted %d idle error patterns", evicted)
+	}
+
 	// Get emerging patterns
 	a.stats.EmergingPatterns = a.patternTracker.GetEmergingPatterns()
 
@@ -262,76 +723,157 @@ func (a *Analyzer) generateStats() *models.LogStats {
 		totalErrorRate += rate
 	}
 
-	if totalErrorRate > 5.0 {
-		a.alertChan <- models.Alert{
-			Timestamp: time.Now(),
+	if totalErrorRate > a.errorAlertThreshold {
+		a.sendAlert(alertHighErrorRate, models.Alert{
+			Timestamp: a.clock.Now(),
 			Message:   fmt.Sprintf("⚠️ High error rate (%.1f errors/sec), increased pattern weight", totalErrorRate),
-		}
+			Severity:  models.SeverityCritical,
+		})
 	}
 
+	// Alert when the error budget burns too fast
+	a.checkSLOBurn(a.stats.SLO)
+
+	// Alert when p99 latency stays high
+	a.checkLatency()
+
+	// Alert when most lines suddenly fail to parse
+	a.checkParseSuccess()
+
+	// Alert when log timestamps are far from the wall clock
+	a.checkClockSkew()
+
+	// Alert when the input has gone silent, and when it resumes
+	a.checkStaleInput(a.clock.Now())
+
+	// Flag single IPs whose errors suddenly dominate, a sign of an abusive client
+	a.checkIPErrorSpikes()
+
 	// Reset buffer resize flag after reporting it once
 	if a.bufferResized {
 		a.bufferResized = false
 	}
 
 	// Return a copy to avoid concurrency issues
-	return a.cloneStats()
+	return a.stats.Clone()
+}
+
+// WindowSnapshot returns a copy of the sliding window contents
+func (a *Analyzer) WindowSnapshot() WindowSnapshot {
+	return a.window.Snapshot()
+}
+
+// rateHistory returns the per-second entry counts of the last seconds
+// completed seconds, oldest first. Seconds without entries count as 0, and
+// the history starts at the first bucket, so it is shorter during startup.
+// Sub-second buckets are summed, longer ones shared out over their seconds.
+func (a *Analyzer) rateHistory(seconds int) []int {
+	buckets := perSecondBuckets(a.rateBuckets, a.bucketInterval)
+	return bucketSeries(buckets, time.Second, time.Duration(seconds)*time.Second, a.clock.Now())
+}
+
+// Snapshot returns a copy of the most recently generated stats
+func (a *Analyzer) Snapshot() *models.LogStats {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.stats.Clone()
+}
+
+// sendAlert sends alert unless another alert of the same category fired
+// within the cooldown. The first alert after a cooldown notes how many
+// repeats were dropped, so a sustained condition reads as still ongoing.
+func (a *Analyzer) sendAlert(category string, alert models.Alert) {
+	a.alertMux.Lock()
+	if last, ok := a.lastAlerts[category]; ok && alert.Timestamp.Sub(last) < a.alertCooldown {
+		a.suppressedCount[category]++
+		a.alertMux.Unlock()
+		return
+	}
+
+	if suppressed := a.suppressedCount[category]; suppressed > 0 {
+		alert.Message += fmt.Sprintf(" (still elevated, %d repeats in the last %s)",
+			suppressed, alert.Timestamp.Sub(a.lastAlerts[category]).Round(time.Second))
+	}
+	a.lastAlerts[category] = alert.Timestamp
+	delete(a.suppressedCount, category)
+	a.alertMux.Unlock()
+
+	a.alertChan <- alert
 }
 
+// alertFatal raises an immediate alert for a critical (e.g. FATAL) entry
+func (a *Analyzer) alertFatal(entry models.LogEntry, now time.Time) {
+	message := entry.Message
+	if message == "" {
+		message = "(no message)"
+	}
+
+	a.alertChan <- models.Alert{
+		Timestamp: now,
+		Message:   fmt.Sprintf("🚨 %s from IP %s: %s", entry.Level, entry.IP, message),
+		Severity:  models.SeverityCritical,
+	}
+
+	if a.debugMode {
+		a.debugLogger.Printf("Critical entry: %s", entry.OriginalLog)
+	}
+}
+
+// checkIPErrorSpikes alerts on IPs whose error count jumped over the last
+// 15 seconds and who now produce a disproportionate share of all errors
+func (a *Analyzer) checkIPErrorSpikes() {
+	if a.ipErrorShareThreshold <= 0 {
+		return
+	}
+
+	for _, change := range a.window.GetIPErrorChanges(15, 15) {
+		// Require the IP's errors to have at least doubled
+		if change.RecentCount < minIPSpikeErrors || change.Change < 100.0 ||
+			change.Share < a.ipErrorShareThreshold {
+			continue
+		}
+
+		a.sendAlert(alertIPSpike+change.IP, models.Alert{
+			Timestamp: a.clock.Now(),
+			Message: fmt.Sprintf("⚠️ IP %s error spike: %.1f errors/sec (%.0f%% of errors, up %.0f%%)",
+				change.IP, change.RecentRate, 100*change.Share, change.Change),
+			Severity: models.SeverityWarning,
+		})
+
+		if a.debugMode {
+			a.debugLogger.Printf("IP %s produced %d errors in 15 sec (%.0f%% of all errors)",
+				change.IP, change.RecentCount, 100*change.Share)
+		}
+	}
+}
+
+// calculateRate returns the average entries/sec of the buckets in the last
+// seconds, or of the latest finalized bucket if they are longer than that
 func (a *Analyzer) calculateRate(seconds int) float64 {
-	now := time.Now()
-	cutoff := now.Add(-time.Duration(seconds) * time.Second)
-	
+	now := a.clock.Now()
+	span := time.Duration(seconds) * time.Second
+	if span < 2*a.bucketInterval {
+		// Reach back far enough to include a finalized bucket
+		span = 2 * a.bucketInterval
+	}
+	cutoff := now.Add(-span)
+
 	var totalCount int
 	var relevantBuckets int
-	
+
 	for _, bucket := range a.rateBuckets {
 		if bucket.Timestamp.After(cutoff) {
 			totalCount += bucket.Count
 			relevantBuckets++
 		}
 	}
-	
+
 	if relevantBuckets == 0 {
 		return 0.0
 	}
-	
-	return float64(totalCount) / float64(relevantBuckets)
-}
-
-func (a *Analyzer) cloneStats() *models.LogStats {
-	clone := models.NewLogStats()
-	
-	clone.EntriesProcessed = a.stats.EntriesProcessed
-	clone.CurrentRate = a.stats.CurrentRate
-	clone.PeakRate = a.stats.PeakRate
-	clone.WindowSize = a.stats.WindowSize
-	clone.PreviousWindowSize = a.stats.PreviousWindowSize
-	clone.LastUpdated = a.stats.LastUpdated
-	clone.SkippedEntries = a.stats.SkippedEntries
-	
-	// Copy maps
-	for k, v := range a.stats.LevelCounts {
-		clone.LevelCounts[k] = v
-	}
-	
-	for k, v := range a.stats.ErrorCounts {
-		clone.ErrorCounts[k] = v
-	}
-	
-	for k, v := range a.stats.ErrorRates {
-		clone.ErrorRates[k] = v
-	}
-	
-	for k, v := range a.stats.EmergingPatterns {
-		clone.EmergingPatterns[k] = v
-	}
-
-	clone.EmergingPatternHistory = make([]models.EmergingPatternEvent, 
-		len(a.stats.EmergingPatternHistory))
-	copy(clone.EmergingPatternHistory, a.stats.EmergingPatternHistory)
-	
-	return clone
+
+	return float64(totalCount) / (float64(relevantBuckets) * a.bucketSeconds())
 }
 
 // Helper functions
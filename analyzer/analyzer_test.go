@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"log_analyzer/models"
+	"log_analyzer/queue"
+)
+
+// startTestAnalyzer starts an analyzer on a queue of the given capacity,
+// discarding its stats and alerts until the returned stop function is
+// called
+func startTestAnalyzer(t *testing.T, capacity int) (*Analyzer, *queue.Queue, func()) {
+	t.Helper()
+
+	entries := queue.New(capacity, queue.PolicyBlock)
+	statsChan := make(chan *models.LogStats)
+	alertChan := make(chan models.Alert)
+	a := NewAnalyzer(entries, statsChan, alertChan, false)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-statsChan:
+			case <-alertChan:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	a.Start()
+	return a, entries, func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func TestConcurrentErrorsAreCountedExactly(t *testing.T) {
+	const (
+		producers   = 8
+		perProducer = 2500
+		total       = producers * perProducer
+	)
+
+	a, entries, stop := startTestAnalyzer(t, 64)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				entries.Push(testEntry(time.Now(), "ERROR", "Timeout", "10.0.0.1"), nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := a.StopAndDrain(10 * time.Second)
+
+	if stats.EntriesProcessed != total {
+		t.Errorf("EntriesProcessed = %d, want %d", stats.EntriesProcessed, total)
+	}
+	if got := stats.LevelCounts["ERROR"]; got != total {
+		t.Errorf("window ERROR count = %d, want %d", got, total)
+	}
+	if got := stats.ErrorCounts["Timeout"]; got != total {
+		t.Errorf("window Timeout count = %d, want %d", got, total)
+	}
+	if got := stats.Lifetime.LevelCounts["ERROR"]; got != total {
+		t.Errorf("lifetime ERROR count = %d, want %d", got, total)
+	}
+}
+
+func TestBaselineIsZeroWhileLearning(t *testing.T) {
+	entries := queue.New(16, queue.PolicyBlock)
+	a := NewAnalyzer(entries, make(chan *models.LogStats, 1), make(chan models.Alert, 100), false)
+	clock := newFakeClock()
+	a.SetClock(clock)
+
+	// One bucket is finalized each second, when the next one's first
+	// entry arrives
+	for sec := 0; sec <= anomalyMinSamples; sec++ {
+		for i := 0; i < 10; i++ {
+			a.processEntry(testEntry(clock.Now(), "INFO", "", "10.0.0.1"))
+		}
+
+		stats := a.generateStats()
+		if sec < anomalyMinSamples {
+			if stats.BaselineRate != 0 || stats.BaselineStdDev != 0 {
+				t.Fatalf("after %d buckets baseline is %g ± %g, want 0 while learning",
+					sec, stats.BaselineRate, stats.BaselineStdDev)
+			}
+		} else if stats.BaselineRate != 10 {
+			t.Errorf("after %d buckets baseline is %g, want 10", sec, stats.BaselineRate)
+		}
+		clock.Advance(time.Second)
+	}
+}
@@ -0,0 +1,146 @@
+// analyzer/anomaly.go
+// This file contains rate anomaly detection against a learned baseline.
+
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// DefaultAnomalySigma is how many standard deviations from the baseline a
+// rate bucket's entry count must be to raise a rate anomaly alert
+const DefaultAnomalySigma = 3.0
+
+const (
+	// anomalyBaselineSec is how many recent seconds make up the baseline
+	anomalyBaselineSec = 120
+	// anomalyMinSamples is how many seconds are learned before alerting
+	anomalyMinSamples = 30
+)
+
+// rateBaseline keeps the running mean and variance of the per-bucket entry
+// counts of the last anomalyBaselineSec seconds, using Welford's algorithm
+// extended to remove the oldest sample as each new one arrives
+type rateBaseline struct {
+	samples    []int         // Counts in the baseline, oldest first
+	mean       float64       // Mean of samples
+	m2         float64       // Sum of squared differences from the mean
+	last       time.Time     // Bucket of the newest sample
+	interval   time.Duration // Length of each bucket
+	size       int           // Buckets in anomalyBaselineSec
+	minSamples int           // Buckets in anomalyMinSamples seconds
+}
+
+// newRateBaseline creates a baseline of buckets of the given interval
+func newRateBaseline(interval time.Duration) *rateBaseline {
+	perSecond := float64(time.Second) / float64(interval)
+	return &rateBaseline{
+		interval:   interval,
+		size:       int(anomalyBaselineSec * perSecond),
+		minSamples: max(2, int(anomalyMinSamples*perSecond)),
+	}
+}
+
+// add appends a sample, evicting the oldest once the baseline is full
+func (b *rateBaseline) add(count int) {
+	b.samples = append(b.samples, count)
+	x := float64(count)
+	delta := x - b.mean
+	b.mean += delta / float64(len(b.samples))
+	b.m2 += delta * (x - b.mean)
+
+	if len(b.samples) > b.size {
+		b.remove()
+	}
+}
+
+// remove drops the oldest sample
+func (b *rateBaseline) remove() {
+	x := float64(b.samples[0])
+	b.samples = b.samples[1:]
+	if len(b.samples) == 0 {
+		b.mean, b.m2 = 0, 0
+		return
+	}
+
+	delta := x - b.mean
+	b.mean -= delta / float64(len(b.samples))
+	b.m2 -= delta * (x - b.mean)
+	if b.m2 < 0 { // Rounding errors can push it slightly negative
+		b.m2 = 0
+	}
+}
+
+// stddev returns the sample standard deviation
+func (b *rateBaseline) stddev() float64 {
+	if len(b.samples) < 2 {
+		return 0
+	}
+	return math.Sqrt(b.m2 / float64(len(b.samples)-1))
+}
+
+// learned reports whether the baseline has enough samples to alert on
+func (b *rateBaseline) learned() bool {
+	return len(b.samples) >= b.minSamples
+}
+
+// SetAnomalySigma sets how many standard deviations from the learned
+// baseline a rate bucket's entry count must be to raise an alert. 0 disables
+// rate anomaly alerts.
+func (a *Analyzer) SetAnomalySigma(sigma float64) error {
+	if sigma < 0 {
+		return fmt.Errorf("sigma must not be negative, got %g", sigma)
+	}
+
+	a.anomalySigma = sigma
+	return nil
+}
+
+// checkRateAnomaly compares a finalized bucket's count against the
+// baseline, then adds it. Buckets without entries since the previous
+// one are added as zeros first, so a drop in traffic is caught too once
+// entries resume. It must be called with a.mux held.
+func (a *Analyzer) checkRateAnomaly(timestamp time.Time, count int) {
+	b := a.baseline
+	if !b.last.IsZero() {
+		missing := int(timestamp.Sub(b.last)/b.interval) - 1
+		for i := 0; i < min(missing, b.size); i++ {
+			a.observeRate(0)
+		}
+	}
+	a.observeRate(count)
+	b.last = timestamp
+}
+
+// observeRate raises an alert if count is anomalous, then adds it to the
+// baseline. The deviation is measured in standard deviations, but never
+// less than the square root of the mean (the noise expected of random
+// arrivals), so a very steady baseline does not flag every small change.
+func (a *Analyzer) observeRate(count int) {
+	b := a.baseline
+	if a.anomalySigma > 0 && b.learned() {
+		spread := math.Max(b.stddev(), math.Sqrt(b.mean))
+		if spread > 0 {
+			deviation := (float64(count) - b.mean) / spread
+			if math.Abs(deviation) > a.anomalySigma {
+				direction := "above"
+				if deviation < 0 {
+					direction = "below"
+				}
+				// Report rates per second, whatever the bucket length
+				seconds := b.interval.Seconds()
+				a.sendAlert(alertRateAnomaly, models.Alert{
+					Timestamp: a.clock.Now(),
+					Message: fmt.Sprintf("⚠️ Rate anomaly: %.0f entries/sec is %.1fσ %s the baseline of %.0f ± %.0f",
+						float64(count)/seconds, math.Abs(deviation), direction, b.mean/seconds, b.stddev()/seconds),
+					Severity: models.SeverityWarning,
+				})
+			}
+		}
+	}
+	b.add(count)
+}
@@ -0,0 +1,142 @@
+// analyzer/buffer.go
+// This file contains the growing and shrinking of the entry queue under bursts.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// Default growth of the entry buffer under bursts
+const (
+	DefaultBufferGrowAt      = 0.8  // Fill ratio at which the buffer grows
+	DefaultBufferGrowFactor  = 1.5  // Factor the capacity grows by
+	DefaultBufferShrinkBelow = 0.25 // Rate, as a fraction of capacity, below which the buffer shrinks
+)
+
+const (
+	// bufferShrinkTicks is how many consecutive stats ticks the rate must
+	// stay low before the buffer shrinks one step
+	bufferShrinkTicks = 10
+	// bufferShrinkInterval is the least time between a resize and a shrink,
+	// so the buffer does not oscillate under bursty load
+	bufferShrinkInterval = 30 * time.Second
+)
+
+// bufferCheckEvery is how many entries are taken off the queue between
+// checks of its fill ratio, as each check locks the queue
+const bufferCheckEvery = 32
+
+// bufferMaxFactor caps the entry queue at this many times its initial
+// capacity, so a sustained overload triggers the queue's overflow policy
+// instead of growing memory without bound
+const bufferMaxFactor = 10
+
+// SetBufferGrowth sets the fill ratio (0-1) of the entry buffer at which it
+// grows, and the factor (above 1) its capacity grows by
+func (a *Analyzer) SetBufferGrowth(growAt, factor float64) error {
+	if growAt <= 0 || growAt > 1 {
+		return fmt.Errorf("grow ratio %.2f must be above 0 and at most 1", growAt)
+	}
+	if factor <= 1 {
+		return fmt.Errorf("grow factor %.2f must be above 1", factor)
+	}
+
+	a.bufferGrowAt = growAt
+	a.bufferGrowFactor = factor
+	return nil
+}
+
+// SetBufferShrink sets the processing rate, as a fraction (0-1) of the
+// entry buffer's capacity, below which a grown buffer shrinks back towards
+// its initial size. 0 disables shrinking.
+func (a *Analyzer) SetBufferShrink(below float64) error {
+	if below < 0 || below > 1 {
+		return fmt.Errorf("shrink ratio %.2f must be between 0 and 1", below)
+	}
+
+	a.bufferShrinkBelow = below
+	return nil
+}
+
+// growBuffer grows the entry queue once it is filled past the grow ratio,
+// raising a burst alert. It must only be called from the goroutine
+// consuming the queue, which calls it every bufferCheckEvery entries.
+func (a *Analyzer) growBuffer() {
+	waiting, capacity := a.entries.Len(), a.entries.Cap()
+	if float64(waiting) < a.bufferGrowAt*float64(capacity) {
+		return
+	}
+
+	newSize := min(int(float64(capacity)*a.bufferGrowFactor), a.initialBufferSize*bufferMaxFactor)
+	if newSize <= capacity {
+		return
+	}
+	newSize = a.entries.Resize(newSize)
+	a.shrinkTo.Store(0) // A shrink requested before the burst no longer applies
+
+	a.mux.Lock()
+	a.bufferSize = newSize
+	a.bufferResized = true
+	a.lastBufferResize = a.clock.Now()
+	a.mux.Unlock()
+
+	a.sendAlert(alertBufferResize, models.Alert{
+		Timestamp: a.clock.Now(),
+		Message:   fmt.Sprintf("⚠️ Burst detected: %d entries waiting, resized buffer to %d", waiting, newSize),
+		Severity:  models.SeverityWarning,
+	})
+
+	if a.debugMode {
+		a.debugLogger.Printf("Resized buffer to %d due to high load", newSize)
+	}
+}
+
+// checkBufferShrink requests a shrink of a grown buffer by one growth
+// step, never below its initial size, once the rate has stayed below the
+// shrink ratio of its capacity for bufferShrinkTicks ticks and it has not
+// been resized for bufferShrinkInterval. Resizing is left to the
+// processing goroutine, which applies the request in shrinkBuffer. It must
+// be called with a.mux held.
+func (a *Analyzer) checkBufferShrink(rate float64) {
+	if a.bufferShrinkBelow <= 0 || a.bufferSize <= a.initialBufferSize ||
+		rate >= a.bufferShrinkBelow*float64(a.bufferSize) {
+		a.bufferLowTicks = 0
+		return
+	}
+
+	a.bufferLowTicks++
+	if a.bufferLowTicks < bufferShrinkTicks || a.clock.Now().Sub(a.lastBufferResize) < bufferShrinkInterval {
+		return
+	}
+	a.bufferLowTicks = 0
+	a.shrinkTo.Store(int64(max(a.initialBufferSize, int(float64(a.bufferSize)/a.bufferGrowFactor))))
+}
+
+// shrinkBuffer applies a shrink requested by checkBufferShrink, raising an
+// alert. It must only be called from the goroutine consuming the queue.
+func (a *Analyzer) shrinkBuffer() {
+	newSize := int(a.shrinkTo.Swap(0))
+	if newSize == 0 || newSize >= a.entries.Cap() {
+		return
+	}
+	newSize = a.entries.Resize(newSize) // Entries still waiting can keep it larger
+
+	a.mux.Lock()
+	a.bufferSize = newSize
+	a.lastBufferResize = a.clock.Now()
+	a.mux.Unlock()
+
+	a.sendAlert(alertBufferShrink, models.Alert{
+		Timestamp: a.clock.Now(),
+		Message:   fmt.Sprintf("⚠️ Load subsided, shrunk buffer to %d", newSize),
+		Severity:  models.SeverityInfo,
+	})
+
+	if a.debugMode {
+		a.debugLogger.Printf("Shrunk buffer to %d after load subsided", newSize)
+	}
+}
@@ -0,0 +1,22 @@
+// analyzer/clock.go
+// This file contains the clock the analyzer reads the current time from.
+
+package analyzer
+
+import "time"
+
+// Clock tells the current time. The analyzer, sliding window and pattern
+// tracker read it instead of calling time.Now directly, so tests can drive
+// window expiry, rate buckets and pattern timing with a fake clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock reads the system clock
+type realClock struct{}
+
+// Now returns time.Now()
+func (realClock) Now() time.Time { return time.Now() }
+
+// RealClock is the default clock, backed by the system clock
+var RealClock Clock = realClock{}
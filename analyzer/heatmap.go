@@ -0,0 +1,76 @@
+// analyzer/heatmap.go
+// This file contains the export of errors by hour of day.
+
+package analyzer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"log_analyzer/models"
+)
+
+// heatmap is the JSON form of the errors by hour of day
+type heatmap struct {
+	Hours  [24]int            `json:"hours"` // UTC hour of day -> ERROR and critical entries
+	ByType map[string][24]int `json:"by_type"`
+}
+
+// WriteHeatmap writes the lifetime errors by UTC hour of day to path, as
+// JSON if path ends in .json and as CSV otherwise. The CSV has one row per
+// error type after a "total" row, and one column per hour.
+func WriteHeatmap(path string, lifetime models.LifetimeStats) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(heatmap{Hours: lifetime.ErrorsByHour, ByType: lifetime.ErrorTypesByHour}, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	} else {
+		data = heatmapCSV(lifetime)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// heatmapCSV renders the errors by hour as CSV, error types sorted by name
+func heatmapCSV(lifetime models.LifetimeStats) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"error_type"}
+	for hour := 0; hour < 24; hour++ {
+		header = append(header, fmt.Sprintf("%02d", hour))
+	}
+	w.Write(header)
+
+	writeRow := func(name string, counts [24]int) {
+		row := []string{name}
+		for _, count := range counts {
+			row = append(row, strconv.Itoa(count))
+		}
+		w.Write(row)
+	}
+
+	writeRow("total", lifetime.ErrorsByHour)
+	types := make([]string, 0, len(lifetime.ErrorTypesByHour))
+	for errType := range lifetime.ErrorTypesByHour {
+		types = append(types, errType)
+	}
+	sort.Strings(types)
+	for _, errType := range types {
+		writeRow(errType, lifetime.ErrorTypesByHour[errType])
+	}
+
+	// Writes to a bytes.Buffer cannot fail
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,72 @@
+// analyzer/hll.go
+// This file contains the HyperLogLog sketch estimating distinct counts in constant memory.
+
+package analyzer
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits picking a register. 2^14
+// one-byte registers take 16KB and give a standard error of about 0.8%.
+const hllPrecision = 14
+
+const hllRegisters = 1 << hllPrecision
+
+// hyperLogLog estimates how many distinct strings it has seen, using the
+// same memory however many there are. It is not safe for concurrent use.
+type hyperLogLog struct {
+	registers [hllRegisters]uint8
+}
+
+// add records a string
+func (h *hyperLogLog) add(s string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(s))
+	x := mix64(hasher.Sum64())
+
+	// The top bits pick the register, which keeps the longest run of leading
+	// zeros seen in the remaining bits
+	index := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// count returns the estimated number of distinct strings added
+func (h *hyperLogLog) count() int {
+	const m = float64(hllRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := alpha * m * m / sum
+
+	// Small cardinalities are estimated better by linear counting of the
+	// registers still empty
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int(math.Round(estimate))
+}
+
+// mix64 scrambles a hash so every bit depends on every input bit; FNV alone
+// leaves the top bits, which pick the register, poorly distributed for
+// similar short strings such as IPs
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,60 @@
+// analyzer/interarrival.go
+// This file contains the histogram of gaps between consecutive valid entries.
+
+package analyzer
+
+import (
+	"time"
+
+	"log_analyzer/models"
+)
+
+// gapBounds are the upper bounds of every histogram bucket but the last
+var gapBounds = []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond}
+
+// gapLabels name the buckets delimited by gapBounds
+var gapLabels = []string{"<1ms", "1-10ms", "10-100ms", ">100ms"}
+
+// gapHistogram counts inter-arrival gaps. Counts are reset once per window
+// duration, so the histogram always describes roughly one window of traffic.
+type gapHistogram struct {
+	counts    []int
+	last      time.Time // Arrival time of the previous entry
+	periodEnd time.Time // When the counts are next reset
+}
+
+func newGapHistogram() *gapHistogram {
+	return &gapHistogram{counts: make([]int, len(gapLabels))}
+}
+
+// observe records an entry arriving at now
+func (h *gapHistogram) observe(now time.Time, window time.Duration) {
+	if now.After(h.periodEnd) {
+		for i := range h.counts {
+			h.counts[i] = 0
+		}
+		h.periodEnd = now.Add(window)
+	}
+
+	if !h.last.IsZero() {
+		gap := now.Sub(h.last)
+		bucket := len(gapBounds)
+		for i, bound := range gapBounds {
+			if gap < bound {
+				bucket = i
+				break
+			}
+		}
+		h.counts[bucket]++
+	}
+	h.last = now
+}
+
+// buckets returns a copy of the current counts
+func (h *gapHistogram) buckets() []models.HistogramBucket {
+	result := make([]models.HistogramBucket, len(h.counts))
+	for i, count := range h.counts {
+		result[i] = models.HistogramBucket{Label: gapLabels[i], Count: count}
+	}
+	return result
+}
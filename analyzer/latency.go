@@ -0,0 +1,68 @@
+// analyzer/latency.go
+// This file contains the alert on high p99 latency over the window.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// DefaultLatencyAlertTicks is how many consecutive stats ticks p99 latency
+// must stay above the threshold before the alert fires
+const DefaultLatencyAlertTicks = 3
+
+// latencyAlert tracks how long p99 latency has been above the threshold.
+// It is guarded by the analyzer's mux.
+type latencyAlert struct {
+	threshold time.Duration // p99 latency that raises the alert, 0 disables it
+	ticks     int           // Consecutive ticks above the threshold needed to alert
+	over      int           // Consecutive ticks above the threshold so far
+}
+
+// SetLatencyAlert sets the p99 latency over the window above which an alert
+// fires, once it has stayed there for ticks consecutive stats ticks (one
+// per second). It needs durations extracted from messages, see
+// reader.SetDurationPattern. A threshold of 0 disables it.
+func (a *Analyzer) SetLatencyAlert(threshold time.Duration, ticks int) error {
+	if threshold < 0 {
+		return fmt.Errorf("latency threshold must not be negative, got %s", threshold)
+	}
+	if ticks < 1 {
+		return fmt.Errorf("latency alert ticks must be at least 1, got %d", ticks)
+	}
+
+	a.latency.threshold = threshold
+	a.latency.ticks = ticks
+	return nil
+}
+
+// checkLatency alerts when p99 latency has been above the threshold for
+// enough consecutive ticks. A tick with no durations in the window breaks
+// the run. It must be called with a.mux held, after the latency stats are
+// updated.
+func (a *Analyzer) checkLatency() {
+	l := &a.latency
+	if l.threshold <= 0 {
+		return
+	}
+
+	thresholdMs := float64(l.threshold) / float64(time.Millisecond)
+	if a.stats.LatencySamples == 0 || a.stats.LatencyP99 <= thresholdMs {
+		l.over = 0
+		return
+	}
+	l.over++
+	if l.over < l.ticks {
+		return
+	}
+
+	a.sendAlert(alertLatency, models.Alert{
+		Timestamp: a.clock.Now(),
+		Message: fmt.Sprintf("🐢 p99 latency %.1fms above %s for %d seconds (%d requests in window)",
+			a.stats.LatencyP99, l.threshold, l.over, a.stats.LatencySamples),
+		Severity: models.SeverityWarning,
+	})
+}
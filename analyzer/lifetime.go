@@ -0,0 +1,131 @@
+// analyzer/lifetime.go
+// This file contains the aggregator of stats since the analyzer started.
+
+package analyzer
+
+import (
+	"time"
+
+	"log_analyzer/models"
+)
+
+// lifetimeMaxPoints bounds the lifetime rate series. When it fills up,
+// neighbouring points are merged and the resolution doubles, so memory stays
+// constant however long the analyzer runs.
+const lifetimeMaxPoints = 60
+
+// lifetimeAggregator counts every valid entry since the analyzer started,
+// independent of the sliding window. It is guarded by the analyzer's mux.
+type lifetimeAggregator struct {
+	since       time.Time
+	levelCounts map[string]int
+	errorCounts map[string]int
+	series      []int     // Entries per point, oldest first
+	resolution  int       // Seconds covered by each point in series
+	seriesStart time.Time // Start of the first point
+
+	// ERROR and critical entries by UTC hour of day, see WriteHeatmap
+	errorsByHour     [24]int
+	errorTypesByHour map[string][24]int
+
+	ips hyperLogLog // Distinct source IPs, estimated to bound memory
+}
+
+// newLifetimeAggregator creates an aggregator starting at since, whose rate
+// series starts at the resolution of rate buckets of the given interval
+func newLifetimeAggregator(since time.Time, bucket time.Duration) *lifetimeAggregator {
+	return &lifetimeAggregator{
+		since:       since,
+		levelCounts: make(map[string]int),
+		errorCounts: make(map[string]int),
+		series:      make([]int, 0, lifetimeMaxPoints),
+		resolution:  max(1, int(bucket/time.Second)),
+
+		errorTypesByHour: make(map[string][24]int),
+	}
+}
+
+// observe counts a valid entry
+func (l *lifetimeAggregator) observe(entry models.LogEntry) {
+	l.levelCounts[entry.Level]++
+	if entry.IP != "" {
+		l.ips.add(entry.IP)
+	}
+	if entry.ErrorType != "" {
+		l.errorCounts[entry.ErrorType]++
+	}
+
+	// Bucket errors by the hour they were logged, not when they were read,
+	// so replayed history shows its real time-of-day pattern
+	if models.LevelSeverity(entry.Level) >= models.SeverityError {
+		hour := entry.Timestamp.UTC().Hour()
+		l.errorsByHour[hour]++
+		if entry.ErrorType != "" {
+			counts := l.errorTypesByHour[entry.ErrorType]
+			counts[hour]++
+			l.errorTypesByHour[entry.ErrorType] = counts
+		}
+	}
+}
+
+// addRate adds a finalized rate bucket to the series
+func (l *lifetimeAggregator) addRate(timestamp time.Time, count int) {
+	if l.seriesStart.IsZero() {
+		l.seriesStart = timestamp
+	}
+	if timestamp.Before(l.seriesStart) {
+		return
+	}
+
+	index := l.pointIndex(timestamp)
+	for index >= lifetimeMaxPoints {
+		l.downsample()
+		index = l.pointIndex(timestamp)
+	}
+	for len(l.series) <= index {
+		l.series = append(l.series, 0)
+	}
+	l.series[index] += count
+}
+
+// pointIndex returns the series point timestamp falls into
+func (l *lifetimeAggregator) pointIndex(timestamp time.Time) int {
+	return int(timestamp.Sub(l.seriesStart) / (time.Duration(l.resolution) * time.Second))
+}
+
+// downsample merges every pair of neighbouring points, halving the series
+func (l *lifetimeAggregator) downsample() {
+	merged := make([]int, (len(l.series)+1)/2, lifetimeMaxPoints)
+	for i, count := range l.series {
+		merged[i/2] += count
+	}
+	l.series = merged
+	l.resolution *= 2
+}
+
+// snapshot returns a copy of the aggregated stats
+func (l *lifetimeAggregator) snapshot() models.LifetimeStats {
+	lifetime := models.LifetimeStats{
+		Since:          l.since,
+		LevelCounts:    make(map[string]int, len(l.levelCounts)),
+		ErrorCounts:    make(map[string]int, len(l.errorCounts)),
+		RateSeries:     make([]int, len(l.series)),
+		RateResolution: l.resolution,
+
+		ErrorsByHour:     l.errorsByHour,
+		ErrorTypesByHour: make(map[string][24]int, len(l.errorTypesByHour)),
+
+		UniqueIPs: l.ips.count(),
+	}
+	for level, count := range l.levelCounts {
+		lifetime.LevelCounts[level] = count
+	}
+	for errType, count := range l.errorCounts {
+		lifetime.ErrorCounts[errType] = count
+	}
+	for errType, counts := range l.errorTypesByHour {
+		lifetime.ErrorTypesByHour[errType] = counts
+	}
+	copy(lifetime.RateSeries, l.series)
+	return lifetime
+}
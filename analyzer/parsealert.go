@@ -0,0 +1,106 @@
+// analyzer/parsealert.go
+// This file contains the alert on a sudden drop of the parse success rate.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// Default parse success alert, catching a log format change that makes
+// most lines fail to parse
+const (
+	DefaultParseAlertThreshold = 90.0             // Parse success rate (%) below which the alert fires
+	DefaultParseAlertWindow    = 10 * time.Second // Period the success rate is measured over
+)
+
+// parseAlertMinLines is how many lines the period needs before alerting, so
+// a few bad lines on a quiet feed are not reported
+const parseAlertMinLines = 20
+
+// parseSample is the lines received and rejected during one stats tick
+type parseSample struct {
+	at       time.Time
+	lines    int
+	rejected int
+}
+
+// parseAlert tracks the parse success rate over the latest period. It is
+// guarded by the analyzer's mux.
+type parseAlert struct {
+	threshold    float64       // Success rate (%) below which the alert fires, 0 disables it
+	window       time.Duration // Period the success rate is measured over
+	samples      []parseSample // Ticks inside the period, oldest first
+	lastLines    int           // Lines received at the previous tick
+	lastRejected int           // Lines rejected at the previous tick
+}
+
+// SetParseAlert sets the parse success rate, in percent, below which an
+// alert fires when it is measured over the latest window. Unlike the
+// success rate since start, it catches a format change mid-stream, e.g. a
+// deploy that changed the log format. 0 disables it.
+func (a *Analyzer) SetParseAlert(threshold float64, window time.Duration) error {
+	if threshold < 0 || threshold > 100 {
+		return fmt.Errorf("parse success threshold %g must be between 0 and 100", threshold)
+	}
+	if window < time.Second {
+		return fmt.Errorf("parse success window must be at least 1s, got %s", window)
+	}
+
+	a.parse.threshold = threshold
+	a.parse.window = window
+	return nil
+}
+
+// checkParseSuccess records the lines received and rejected since the last
+// tick, and alerts when the success rate over the window is below the
+// threshold. It must be called with a.mux held, after the line counts are
+// updated.
+func (a *Analyzer) checkParseSuccess() {
+	p := &a.parse
+	if p.threshold <= 0 {
+		return
+	}
+
+	// A stats rotation resets the counts, so they start over from 0
+	if a.totalLines < p.lastLines || a.skippedEntries < p.lastRejected {
+		p.lastLines, p.lastRejected = 0, 0
+	}
+	now := a.clock.Now()
+	p.samples = append(p.samples, parseSample{
+		at:       now,
+		lines:    a.totalLines - p.lastLines,
+		rejected: a.skippedEntries - p.lastRejected,
+	})
+	p.lastLines, p.lastRejected = a.totalLines, a.skippedEntries
+
+	cutoff := now.Add(-p.window)
+	expired := 0
+	for expired < len(p.samples) && !p.samples[expired].at.After(cutoff) {
+		expired++
+	}
+	p.samples = p.samples[expired:]
+
+	lines, rejected := 0, 0
+	for _, sample := range p.samples {
+		lines += sample.lines
+		rejected += sample.rejected
+	}
+	if lines < parseAlertMinLines {
+		return
+	}
+	success := 100 * float64(lines-rejected) / float64(lines)
+	if success >= p.threshold {
+		return
+	}
+
+	a.sendAlert(alertParseFailure, models.Alert{
+		Timestamp: now,
+		Message: fmt.Sprintf("🧩 Parse success rate dropped to %.1f%% over the last %s (%d of %d lines failed), has the log format changed?",
+			success, p.window, rejected, lines),
+		Severity: models.SeverityCritical,
+	})
+}
@@ -5,6 +5,7 @@ package analyzer
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -14,10 +15,39 @@ import (
 
 // ErrorPattern tracks statistics for an error pattern
 type ErrorPattern struct {
-	Count       int
-	Weight      float64
-	LastUpdated time.Time
-	RateHistory []float64 // Stores rates for the last few time periods
+	Count         int
+	Weight        float64   // Weight as of WeightUpdated, decaying towards 1 from then on
+	WeightUpdated time.Time // When Weight was last boosted or decayed
+	LastUpdated   time.Time
+	RateHistory   []float64 // Stores rates for the last few time periods
+}
+
+// PatternConfig tunes emerging-pattern detection and weighting
+type PatternConfig struct {
+	ThresholdPercent float64       // Increase (%) over the previous period reported as emerging
+	RecentSec        int           // Length of the recent comparison period
+	PrevSec          int           // Length of the previous comparison period
+	MinCount         int           // Fewest errors in the recent period for a change to be reported
+	SpikeMultiplier  float64       // Rate increase factor that boosts a pattern's weight
+	WeightFactor     float64       // Factor the weight is multiplied by on a spike
+	WeightHalfLife   time.Duration // Time for a boosted weight's excess over 1 to halve, 0 never decays
+	IdleEviction     time.Duration // Age past which a pattern absent from the window is forgotten, 0 keeps all
+}
+
+// DefaultPatternConfig reports >100% increases of at least 3 errors over
+// 15s-vs-15s windows and triples a pattern's weight when its rate quadruples, halving the boost
+// every 5 minutes and forgetting error types idle for 10 minutes
+func DefaultPatternConfig() PatternConfig {
+	return PatternConfig{
+		ThresholdPercent: 100.0,
+		RecentSec:        15,
+		PrevSec:          15,
+		MinCount:         3,
+		SpikeMultiplier:  4.0,
+		WeightFactor:     3.0,
+		WeightHalfLife:   5 * time.Minute,
+		IdleEviction:     10 * time.Minute,
+	}
 }
 
 // PatternTracker tracks error patterns and their weights
@@ -25,8 +55,10 @@ type PatternTracker struct {
 	patterns       map[string]*ErrorPattern
 	window         *SlidingWindow
 	mux            sync.RWMutex
+	config         PatternConfig
 	historySize    int
 	patternHistory []models.EmergingPatternEvent // Store pattern history here instead of in analyzer
+	clock          Clock
 }
 
 // NewPatternTracker creates a new pattern tracker
@@ -34,11 +66,47 @@ func NewPatternTracker(window *SlidingWindow) *PatternTracker {
 	return &PatternTracker{
 		patterns:       make(map[string]*ErrorPattern),
 		window:         window,
+		config:         DefaultPatternConfig(),
 		historySize:    5, // Keep 5 time periods of history
 		patternHistory: make([]models.EmergingPatternEvent, 0, 5), // Initialize history slice
+		clock:          RealClock,
 	}
 }
 
+// SetClock replaces the clock rate history and pattern events are timed by
+func (pt *PatternTracker) SetClock(clock Clock) {
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+
+	pt.clock = clock
+}
+
+// SetConfig replaces the detection and weighting parameters
+func (pt *PatternTracker) SetConfig(config PatternConfig) error {
+	switch {
+	case config.ThresholdPercent < 0:
+		return fmt.Errorf("threshold must not be negative, got %g", config.ThresholdPercent)
+	case config.RecentSec <= 0 || config.PrevSec <= 0:
+		return fmt.Errorf("comparison periods must be positive, got %ds and %ds", config.RecentSec, config.PrevSec)
+	case config.MinCount < 0:
+		return fmt.Errorf("minimum count must not be negative, got %d", config.MinCount)
+	case config.SpikeMultiplier < 1:
+		return fmt.Errorf("spike multiplier must be at least 1, got %g", config.SpikeMultiplier)
+	case config.WeightFactor < 1:
+		return fmt.Errorf("weight factor must be at least 1, got %g", config.WeightFactor)
+	case config.WeightHalfLife < 0:
+		return fmt.Errorf("weight half-life must not be negative, got %s", config.WeightHalfLife)
+	case config.IdleEviction < 0:
+		return fmt.Errorf("idle eviction age must not be negative, got %s", config.IdleEviction)
+	}
+
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+
+	pt.config = config
+	return nil
+}
+
 // UpdatePattern updates the statistics for an error pattern
 func (pt *PatternTracker) UpdatePattern(entry models.LogEntry) {
 	if entry.Level != "ERROR" || entry.ErrorType == "" {
@@ -51,14 +119,16 @@ func (pt *PatternTracker) UpdatePattern(entry models.LogEntry) {
 	pattern, exists := pt.patterns[entry.ErrorType]
 	if !exists {
 		pattern = &ErrorPattern{
-			RateHistory: make([]float64, pt.historySize),
-			LastUpdated: time.Now(),
+			Weight:        1.0, // Multiplier, so tripling it has an effect
+			WeightUpdated: pt.clock.Now(),
+			RateHistory:   make([]float64, pt.historySize),
+			LastUpdated:   pt.clock.Now(),
 		}
 		pt.patterns[entry.ErrorType] = pattern
 	}
 
 	pattern.Count++
-	now := time.Now()
+	now := pt.clock.Now()
 
 	// Update rate history every 10 seconds
 	if now.Sub(pattern.LastUpdated) > 10*time.Second {
@@ -67,73 +137,121 @@ func (pt *PatternTracker) UpdatePattern(entry models.LogEntry) {
 		pattern.RateHistory[0] = pt.window.GetErrorRate(entry.ErrorType, 10)
 		pattern.LastUpdated = now
 
-		// Check for quadrupling of rate
-		if len(pattern.RateHistory) >= 2 && 
-           pattern.RateHistory[0] > 0 && 
-           pattern.RateHistory[1] > 0 && 
-           pattern.RateHistory[0] >= 4*pattern.RateHistory[1] {
-			// Triple the weight if the rate quadrupled
-			pattern.Weight = pattern.Weight*3.0
+		// Check whether the rate spiked (quadrupled by default)
+		if len(pattern.RateHistory) >= 2 &&
+			pattern.RateHistory[0] > 0 &&
+			pattern.RateHistory[1] > 0 &&
+			pattern.RateHistory[0] >= pt.config.SpikeMultiplier*pattern.RateHistory[1] {
+			// Boost the decayed weight (tripled by default)
+			pattern.Weight = pt.weightAt(pattern, now) * pt.config.WeightFactor
+			pattern.WeightUpdated = now
 		}
 	}
 }
 
-// WeightedError represents an error with its count and weight
-type WeightedError struct {
-	Type   string
-	Count  int
-	Weight float64
-}
+// GetTopErrors returns the top N errors in the window by weighted count,
+// so error types whose rate recently quadrupled rank above their raw count
+func (pt *PatternTracker) GetTopErrors(n int) []models.WeightedError {
+	_, _, errorCounts := pt.window.GetStats()
 
-// GetTopErrors returns the top N errors by weighted count
-func (pt *PatternTracker) GetTopErrors(n int) []WeightedError {
 	pt.mux.RLock()
 	defer pt.mux.RUnlock()
 
-	if len(pt.patterns) == 0 {
-		return []WeightedError{}
-	}
+	now := pt.clock.Now()
+	result := make([]models.WeightedError, 0, len(errorCounts))
+	for errType, count := range errorCounts {
+		if count <= 0 {
+			continue
+		}
 
-	var result []WeightedError
-	for errType, pattern := range pt.patterns {
-		result = append(result, WeightedError{
+		weight := 1.0
+		if pattern, ok := pt.patterns[errType]; ok {
+			weight = pt.weightAt(pattern, now)
+		}
+		result = append(result, models.WeightedError{
 			Type:   errType,
-			Count:  pattern.Count,
-			Weight: pattern.Weight,
+			Count:  count,
+			Weight: weight,
 		})
 	}
 
-	// Sort by weighted count (count * weight)
+	// Sort by weighted count (count * weight), breaking ties by type
 	sort.Slice(result, func(i, j int) bool {
-		weightedI := float64(result[i].Count) * (1.0 + result[i].Weight)
-		weightedJ := float64(result[j].Count) * (1.0 + result[j].Weight)
-		return weightedI > weightedJ
+		weightedI := float64(result[i].Count) * result[i].Weight
+		weightedJ := float64(result[j].Count) * result[j].Weight
+		if weightedI != weightedJ {
+			return weightedI > weightedJ
+		}
+		return result[i].Type < result[j].Type
 	})
 
-	if n > len(result) {
-		n = len(result)
+	if n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+// PruneIdle forgets error types that no longer occur: those whose rate
+// history was last updated more than IdleEviction ago and that have no
+// entries left in the window. It returns how many were evicted, so memory
+// stays bounded with many short-lived (e.g. fingerprinted) error types.
+func (pt *PatternTracker) PruneIdle() int {
+	_, _, errorCounts := pt.window.GetStats()
+
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+
+	if pt.config.IdleEviction <= 0 {
+		return 0
 	}
-	return result[:n]
+
+	cutoff := pt.clock.Now().Add(-pt.config.IdleEviction)
+	evicted := 0
+	for errType, pattern := range pt.patterns {
+		if pattern.LastUpdated.Before(cutoff) && errorCounts[errType] <= 0 {
+			delete(pt.patterns, errType)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// weightAt returns the pattern's weight at now, its excess over 1 having
+// halved every WeightHalfLife since it was last boosted. The caller must
+// hold the lock.
+func (pt *PatternTracker) weightAt(pattern *ErrorPattern, now time.Time) float64 {
+	halfLife := pt.config.WeightHalfLife
+	elapsed := now.Sub(pattern.WeightUpdated)
+	if halfLife <= 0 || elapsed <= 0 {
+		return pattern.Weight
+	}
+	return 1 + (pattern.Weight-1)*math.Pow(0.5, float64(elapsed)/float64(halfLife))
 }
 
 // StoreEmergingPattern stores a significant pattern in history
 func (pt *PatternTracker) StoreEmergingPattern(pattern string, change float64) {
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
+
+	pt.storeEmergingPattern(pattern, change)
+}
+
+// storeEmergingPattern appends a pattern event to the history. The caller
+// must hold the write lock.
+func (pt *PatternTracker) storeEmergingPattern(pattern string, change float64) {
 	// Create a new event for the pattern history
+	now := pt.clock.Now()
 	event := models.EmergingPatternEvent{
 		Pattern:     pattern,
-		StartTime:   time.Now(),
-		EndTime:     time.Now().Add(60 * time.Second), // Keep visible for 60 seconds
+		StartTime:   now,
+		EndTime:     now.Add(60 * time.Second), // Keep visible for 60 seconds
 		PeakChange:  change,
 		Description: fmt.Sprintf("Spike in %s errors", pattern),
 	}
-	
-	// Use pattern tracker's own lock instead of analyzer's lock to avoid deadlock
-	pt.mux.Lock()
-	defer pt.mux.Unlock()
-	
+
 	// Store locally in the pattern tracker instead of in the analyzer
 	pt.patternHistory = append(pt.patternHistory, event)
-	
+
 	// Keep only the last 5 events
 	if len(pt.patternHistory) > 5 {
 		pt.patternHistory = pt.patternHistory[1:]
@@ -152,36 +270,22 @@ func (pt *PatternTracker) GetPatternHistory() []models.EmergingPatternEvent {
 	return result
 }
 
-// GetEmergingPatterns returns patterns with significant recent changes
+// GetEmergingPatterns returns patterns with significant recent changes and
+// records them in the pattern history. Everything happens under a single
+// write lock, so concurrent callers cannot interleave.
 func (pt *PatternTracker) GetEmergingPatterns() map[string]float64 {
-	pt.mux.RLock()
-	defer pt.mux.RUnlock()
+	pt.mux.Lock()
+	defer pt.mux.Unlock()
 
 	result := make(map[string]float64)
-	significantPatterns := make([]string, 0)
-	significantChanges := make([]float64, 0)
-	
-	// First collect all significant patterns without modifying anything
 	for errType := range pt.patterns {
-		// Calculate percentage change in the last 15 seconds compared to previous 15
-		change := pt.window.GetErrorChange(errType, 15, 15)
-		if change > 100.0 { // Only report significant increases (>100%)
+		// Calculate percentage change in the recent period compared to the previous one
+		change := pt.window.GetErrorChange(errType, pt.config.RecentSec, pt.config.PrevSec, pt.config.MinCount)
+		if change > pt.config.ThresholdPercent { // Only report significant increases
 			result[errType] = change
-			significantPatterns = append(significantPatterns, errType)
-			significantChanges = append(significantChanges, change)
+			pt.storeEmergingPattern(errType, change)
 		}
 	}
-	
-	// Release read lock before calling StoreEmergingPattern
-	pt.mux.RUnlock()
-	
-	// Now store each significant pattern separately
-	for i, pattern := range significantPatterns {
-		pt.StoreEmergingPattern(pattern, significantChanges[i])
-	}
-	
-	// Re-acquire read lock for return
-	pt.mux.RLock()
-	
+
 	return result
 }
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestTracker returns a pattern tracker over a 60s window, both on the
+// same fake clock
+func newTestTracker() (*PatternTracker, *SlidingWindow, *fakeClock) {
+	w, clock := newTestWindow(60)
+	pt := NewPatternTracker(w)
+	pt.SetClock(clock)
+	return pt, w, clock
+}
+
+func TestEmergingPatternsDuringIngestion(t *testing.T) {
+	const (
+		writers   = 4
+		perWriter = 500
+	)
+	errorTypes := []string{"Timeout", "Connection Refused", "Disk Full", "Auth Failed"}
+
+	pt, w, clock := newTestTracker()
+
+	var writersDone sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		errType := errorTypes[i]
+		writersDone.Add(1)
+		go func() {
+			defer writersDone.Done()
+			for n := 0; n < perWriter; n++ {
+				entry := testEntry(clock.Now(), "ERROR", errType, "10.0.0.1")
+				w.Add(entry)
+				pt.UpdatePattern(entry)
+			}
+		}()
+	}
+
+	threshold := DefaultPatternConfig().ThresholdPercent
+	stop := make(chan struct{})
+	var readersDone sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readersDone.Add(1)
+		go func() {
+			defer readersDone.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for errType, change := range pt.GetEmergingPatterns() {
+					if change <= threshold {
+						t.Errorf("%s reported at %.0f%%, below the threshold", errType, change)
+					}
+				}
+				if history := pt.GetPatternHistory(); len(history) > 5 {
+					t.Errorf("pattern history holds %d events, want at most 5", len(history))
+				}
+				pt.GetTopErrors(3)
+			}
+		}()
+	}
+
+	writersDone.Wait()
+	close(stop)
+	readersDone.Wait()
+
+	// Every error type went from nothing to perWriter errors
+	emerging := pt.GetEmergingPatterns()
+	want := percentChange(perWriter, 0)
+	for _, errType := range errorTypes {
+		if got, ok := emerging[errType]; !ok || got != want {
+			t.Errorf("%s: change %.0f%% (reported %v), want %.0f%%", errType, got, ok, want)
+		}
+	}
+}
+
+func TestEmergingPatternsFromZero(t *testing.T) {
+	tests := []struct {
+		name     string
+		minCount int
+		prev     int
+		recent   int
+		want     float64 // 0 when not reported
+	}{
+		{"0 to 1 is below the minimum count", 3, 0, 1, 0},
+		{"0 to 2 is below the minimum count", 3, 0, 2, 0},
+		{"0 to the minimum count", 3, 0, 3, 200},
+		{"0 to many", 3, 0, 50, 4900},
+		{"0 to 1 without a minimum count", 0, 0, 1, 0},
+		{"0 to 2 without a minimum count", 0, 0, 2, 0}, // 100% is not above the threshold
+		{"1 to many", 3, 1, 50, 4900},
+		{"steady", 3, 10, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, w, clock := newTestTracker()
+			config := DefaultPatternConfig()
+			config.MinCount = tt.minCount
+			if err := pt.SetConfig(config); err != nil {
+				t.Fatal(err)
+			}
+
+			add := func(n int) {
+				for i := 0; i < n; i++ {
+					entry := testEntry(clock.Now(), "ERROR", "Timeout", "10.0.0.1")
+					w.Add(entry)
+					pt.UpdatePattern(entry)
+				}
+			}
+
+			// The previous 15s period, then the recent one
+			add(tt.prev)
+			clock.Advance(16 * time.Second)
+			add(tt.recent)
+
+			got := pt.GetEmergingPatterns()["Timeout"]
+			if got != tt.want {
+				t.Errorf("change = %.0f%%, want %.0f%%", got, tt.want)
+			}
+		})
+	}
+}
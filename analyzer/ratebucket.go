@@ -0,0 +1,72 @@
+// analyzer/ratebucket.go
+// This file contains the configurable interval entries are counted in for rates.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultRateBucket is the interval entries are counted in for rates
+const DefaultRateBucket = time.Second
+
+// minRateBucket is the shortest rate bucket accepted
+const minRateBucket = 10 * time.Millisecond
+
+// SetRateBucket sets the interval entries are counted in for the
+// processing rate, the rate history and exports. Sub-second buckets give
+// smoother rates for very busy feeds, buckets of several seconds less noisy
+// ones for quiet feeds. The interval must divide a second evenly, down to
+// 10ms, or be 1, 2, 5 or 10 seconds, so buckets line up with the coarser
+// rate tiers. Rates are still reported per second, and the anomaly baseline
+// keeps as many buckets as fit in its 120 seconds, comparing whole buckets.
+// Call it before Start.
+func (a *Analyzer) SetRateBucket(interval time.Duration) error {
+	valid := false
+	if interval >= minRateBucket && interval < time.Second {
+		valid = time.Second%interval == 0
+	} else if interval >= time.Second {
+		valid = interval%time.Second == 0 && rateTierSpecs[0].resolution%interval == 0
+	}
+	if !valid {
+		return fmt.Errorf("rate bucket must divide a second evenly (at least %s) or be 1, 2, 5 or 10 seconds, got %s",
+			minRateBucket, interval)
+	}
+
+	a.bucketInterval = interval
+	a.bucketStart = a.clock.Now().Truncate(interval)
+	a.baseline = newRateBaseline(interval)
+	a.lifetime = newLifetimeAggregator(a.lifetime.since, interval)
+	return nil
+}
+
+// bucketSeconds returns the length of a rate bucket in seconds, which
+// converts a bucket's count into entries/sec
+func (a *Analyzer) bucketSeconds() float64 {
+	return a.bucketInterval.Seconds()
+}
+
+// perSecondBuckets splits buckets longer than a second into one bucket per
+// second, sharing the count out evenly, so per-second series stay smooth
+func perSecondBuckets(buckets []*RateBucket, interval time.Duration) []*RateBucket {
+	if interval <= time.Second {
+		return buckets
+	}
+
+	n := int(interval / time.Second)
+	split := make([]*RateBucket, 0, len(buckets)*n)
+	for _, bucket := range buckets {
+		for i := 0; i < n; i++ {
+			count := bucket.Count / n
+			if i < bucket.Count%n {
+				count++
+			}
+			split = append(split, &RateBucket{
+				Count:     count,
+				Timestamp: bucket.Timestamp.Add(time.Duration(i) * time.Second),
+			})
+		}
+	}
+	return split
+}
@@ -0,0 +1,50 @@
+// analyzer/ratecsv.go
+// This file contains the CSV exporter for finalized rate buckets.
+
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// rateCSV appends one timestamp,count row per finalized rate bucket. Rows
+// go straight to the file without buffering, so the file stays usable even
+// if the process is killed.
+type rateCSV struct {
+	file *os.File
+}
+
+// openRateCSV opens path for appending, writing the header if it is new
+func openRateCSV(path string) (*rateCSV, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		if _, err := fmt.Fprintln(f, "timestamp,count"); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return &rateCSV{file: f}, nil
+}
+
+// write appends a row for one bucket
+func (c *rateCSV) write(timestamp time.Time, count int) error {
+	_, err := fmt.Fprintf(c.file, "%s,%d\n", timestamp.UTC().Format(time.RFC3339Nano), count)
+	return err
+}
+
+// close closes the underlying file
+func (c *rateCSV) close() error {
+	return c.file.Close()
+}
@@ -0,0 +1,119 @@
+// analyzer/ratetiers.go
+// This file contains the coarser rate buckets kept once the finest rate buckets age out.
+
+package analyzer
+
+import (
+	"time"
+
+	"log_analyzer/models"
+)
+
+// rateTierSpecs are the coarser resolutions rate buckets are folded
+// into as they age, each keeping its buckets for retention before passing
+// them on to the next. Buckets leaving the last tier are dropped, which
+// bounds the total to 120 (with 1s rate buckets) + 60 + 1440 buckets.
+var rateTierSpecs = []struct {
+	resolution time.Duration
+	retention  time.Duration
+}{
+	{10 * time.Second, 10 * time.Minute},
+	{time.Minute, 24 * time.Hour},
+}
+
+// rateTier holds buckets of one coarse resolution, oldest first
+type rateTier struct {
+	resolution time.Duration
+	retention  time.Duration
+	buckets    []*RateBucket
+}
+
+func newRateTiers() []*rateTier {
+	tiers := make([]*rateTier, len(rateTierSpecs))
+	for i, spec := range rateTierSpecs {
+		tiers[i] = &rateTier{resolution: spec.resolution, retention: spec.retention}
+	}
+	return tiers
+}
+
+// add merges a finer bucket into the tier's bucket covering its timestamp.
+// Buckets arrive in order, so only the newest bucket can cover it.
+func (t *rateTier) add(bucket *RateBucket) {
+	timestamp := bucket.Timestamp.Truncate(t.resolution)
+	if n := len(t.buckets); n > 0 && t.buckets[n-1].Timestamp.Equal(timestamp) {
+		t.buckets[n-1].Count += bucket.Count
+		return
+	}
+	t.buckets = append(t.buckets, &RateBucket{Count: bucket.Count, Timestamp: timestamp})
+}
+
+// expire removes and returns the buckets older than the tier's retention
+func (t *rateTier) expire(now time.Time) []*RateBucket {
+	cutoff := now.Add(-t.retention)
+	n := 0
+	for n < len(t.buckets) && !t.buckets[n].Timestamp.After(cutoff) {
+		n++
+	}
+	expired := t.buckets[:n:n]
+	t.buckets = t.buckets[n:]
+	return expired
+}
+
+// foldRateBuckets passes rate buckets that aged out down the tiers.
+// It must be called with a.mux held.
+func (a *Analyzer) foldRateBuckets(expired []*RateBucket, now time.Time) {
+	for _, tier := range a.rateTiers {
+		for _, bucket := range expired {
+			tier.add(bucket)
+		}
+		expired = tier.expire(now)
+	}
+}
+
+// rateTierSeries returns the entry counts at every resolution, from the
+// per-second buckets of the last two minutes to the per-minute buckets of
+// the last day. Each series covers its whole span at its own resolution,
+// folding in finer buckets, and starts at the oldest data it has.
+// It must be called with a.mux held.
+func (a *Analyzer) rateTierSeries() []models.RateTier {
+	now := a.clock.Now()
+	series := []models.RateTier{{ResolutionSec: 1, Counts: a.rateHistory(120)}}
+	for i, tier := range a.rateTiers {
+		buckets := append([]*RateBucket(nil), a.rateBuckets...)
+		for _, finer := range a.rateTiers[:i+1] {
+			buckets = append(buckets, finer.buckets...)
+		}
+		series = append(series, models.RateTier{
+			ResolutionSec: int(tier.resolution / time.Second),
+			Counts:        bucketSeries(buckets, tier.resolution, tier.retention, now),
+		})
+	}
+	return series
+}
+
+// bucketSeries sums buckets into points of resolution over the last span,
+// oldest first, ending at now. Points without entries count as 0, the still incomplete
+// current point is left out and the series starts at the oldest bucket.
+func bucketSeries(buckets []*RateBucket, resolution, span time.Duration, now time.Time) []int {
+	end := now.Truncate(resolution)
+	start := end.Add(-span)
+
+	counts := make(map[time.Time]int)
+	first := end
+	for _, bucket := range buckets {
+		point := bucket.Timestamp.Truncate(resolution)
+		if point.Before(start) || !point.Before(end) {
+			continue
+		}
+		counts[point] += bucket.Count
+		if point.Before(first) {
+			first = point
+		}
+	}
+
+	series := make([]int, 0, int(end.Sub(first)/resolution))
+	for t := first; t.Before(end); t = t.Add(resolution) {
+		series = append(series, counts[t])
+	}
+	return series
+}
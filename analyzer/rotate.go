@@ -0,0 +1,66 @@
+// analyzer/rotate.go
+// This file contains the scheduled reset of the cumulative stats.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// SetStatsRotation resets the cumulative stats at every multiple of every,
+// e.g. on the hour for time.Hour (boundaries are aligned to UTC), so each
+// period starts clean: entries processed, the peak rate, the line, skipped
+// and filtered counts and the lifetime totals, including the SLO budget
+// and the heatmap. The sliding window and current rate are unaffected. An
+// alert marks each boundary. 0 disables rotation.
+func (a *Analyzer) SetStatsRotation(every time.Duration) error {
+	if every < 0 {
+		return fmt.Errorf("rotation interval must not be negative, got %s", every)
+	}
+
+	a.rotateEvery = every
+	return nil
+}
+
+// rotateStats resets the cumulative stats once now has crossed a rotation
+// boundary. It must be called with a.mux held, before the stats are
+// generated.
+func (a *Analyzer) rotateStats(now time.Time) {
+	if a.rotateEvery <= 0 {
+		return
+	}
+
+	boundary := now.Truncate(a.rotateEvery)
+	if a.rotatedAt.IsZero() {
+		// The first period runs from the start to the next boundary
+		a.rotatedAt = boundary
+		return
+	}
+	if !boundary.After(a.rotatedAt) {
+		return
+	}
+
+	message := fmt.Sprintf("🔄 Stats rotated: %d entries processed since %s, peak %.0f entries/sec",
+		a.stats.EntriesProcessed, a.lifetime.since.Format(time.TimeOnly), a.stats.PeakRate)
+
+	a.rotatedAt = boundary
+	a.stats.EntriesProcessed = 0
+	a.stats.PeakRate = 0
+	a.totalLines = 0
+	a.skippedEntries = 0
+	a.filteredEntries = 0
+	a.rejectCounts = make(map[string]int)
+	a.lifetime = newLifetimeAggregator(boundary, a.bucketInterval)
+
+	a.alertChan <- models.Alert{
+		Timestamp: now,
+		Message:   message,
+		Severity:  models.SeverityInfo,
+	}
+	if a.debugMode {
+		a.debugLogger.Printf("Rotated stats at %s", boundary.Format(time.RFC3339))
+	}
+}
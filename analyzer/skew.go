@@ -0,0 +1,89 @@
+// analyzer/skew.go
+// This file contains the check for skew between log timestamps and the wall clock.
+
+package analyzer
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// DefaultSkewThreshold is how far the median log timestamp may be from the
+// wall clock before a clock skew alert fires
+const DefaultSkewThreshold = 5 * time.Minute
+
+const (
+	// skewSamples is how many of the latest entries the median skew is taken over
+	skewSamples = 101
+	// skewMinSamples is how many entries are needed before alerting
+	skewMinSamples = 10
+)
+
+// skewTracker keeps the skew of the latest entries, their arrival time
+// minus their own timestamp, in a ring. It is guarded by the analyzer's mux.
+type skewTracker struct {
+	threshold time.Duration   // Median skew that raises the alert, 0 disables it
+	logTime   bool            // Set with TimeLog, where skew is expected and harmless
+	samples   []time.Duration // Ring of the latest skews
+	next      int             // Index the next sample is written to
+}
+
+// SetSkewThreshold sets how far the median timestamp of the latest entries
+// may be behind or ahead of the wall clock before a warning alert fires.
+// Rates mix both clocks, so a large skew, e.g. from a wrong timezone or
+// old data, makes them wrong. The check is skipped with TimeLog. 0
+// disables it.
+func (a *Analyzer) SetSkewThreshold(threshold time.Duration) error {
+	if threshold < 0 {
+		return fmt.Errorf("skew threshold must not be negative, got %s", threshold)
+	}
+
+	a.skew.threshold = threshold
+	return nil
+}
+
+// observe records the skew of an entry that arrived at now
+func (s *skewTracker) observe(now, timestamp time.Time) {
+	skew := now.Sub(timestamp)
+	if len(s.samples) < skewSamples {
+		s.samples = append(s.samples, skew)
+		return
+	}
+	s.samples[s.next] = skew
+	s.next = (s.next + 1) % skewSamples
+}
+
+// median returns the median skew of the latest entries
+func (s *skewTracker) median() time.Duration {
+	sorted := slices.Clone(s.samples)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// checkClockSkew alerts when the median skew of the latest entries exceeds
+// the threshold. It must be called with a.mux held.
+func (a *Analyzer) checkClockSkew() {
+	s := &a.skew
+	if s.threshold <= 0 || s.logTime || len(s.samples) < skewMinSamples {
+		return
+	}
+
+	skew := s.median()
+	direction := "behind"
+	if skew < 0 {
+		skew, direction = -skew, "ahead of"
+	}
+	if skew <= s.threshold {
+		return
+	}
+
+	a.sendAlert(alertClockSkew, models.Alert{
+		Timestamp: a.clock.Now(),
+		Message: fmt.Sprintf("🕒 Log timestamps are %s %s the wall clock (median of the last %d entries), so rates may be wrong; check the log timezone, or measure time from the logs for old data",
+			skew.Round(time.Second), direction, len(s.samples)),
+		Severity: models.SeverityWarning,
+	})
+}
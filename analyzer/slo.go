@@ -0,0 +1,142 @@
+// analyzer/slo.go
+// This file contains the error budget tracking against a success-rate objective.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+const (
+	// sloFastWindow and sloSlowWindow are the windows of the multi-window
+	// burn-rate alert. The fast window makes the alert react quickly, the
+	// slow one keeps a short blip from raising it.
+	sloFastWindow = 5 * time.Minute
+	sloSlowWindow = time.Hour
+	// sloBucket is the resolution entries are counted at for the windows
+	sloBucket = 10 * time.Second
+	// sloBurnThreshold is the burn rate both windows must exceed to alert.
+	// At 14.4 times the sustainable rate, a 30-day budget lasts about two
+	// days, and an hour of it spends 2% of the budget.
+	sloBurnThreshold = 14.4
+)
+
+// sloCount counts entries and failed entries in one sloBucket interval
+type sloCount struct {
+	start  time.Time
+	total  int
+	failed int
+}
+
+// sloTracker counts entries over the slow burn-rate window. It is guarded
+// by the analyzer's mux.
+type sloTracker struct {
+	target  float64    // Success-rate objective in percent, 0 disables tracking
+	buckets []sloCount // Oldest first, covering at most sloSlowWindow
+}
+
+// SetSLO sets the success-rate objective in percent, e.g. 99.9. ERROR and
+// critical entries count as failures. The remaining error budget is
+// reported in the stats, and an alert fires when the budget burns more
+// than 14.4 times faster than the objective allows over both the last 5
+// minutes and the last hour. 0 disables SLO tracking.
+func (a *Analyzer) SetSLO(target float64) error {
+	if target < 0 || target >= 100 {
+		return fmt.Errorf("target must be at least 0 and below 100 percent, got %g", target)
+	}
+
+	a.slo.target = target
+	return nil
+}
+
+// burnsBudget reports whether entries of level count against the error budget
+func burnsBudget(level string) bool {
+	return models.LevelSeverity(level) >= models.SeverityError
+}
+
+// observe counts an entry at now and drops buckets past the slow window
+func (t *sloTracker) observe(now time.Time, failed bool) {
+	if t.target == 0 {
+		return
+	}
+
+	start := now.Truncate(sloBucket)
+	if n := len(t.buckets); n == 0 || t.buckets[n-1].start.Before(start) {
+		t.buckets = append(t.buckets, sloCount{start: start})
+	}
+	bucket := &t.buckets[len(t.buckets)-1]
+	bucket.total++
+	if failed {
+		bucket.failed++
+	}
+
+	cutoff := start.Add(-sloSlowWindow)
+	expired := 0
+	for expired < len(t.buckets) && !t.buckets[expired].start.After(cutoff) {
+		expired++
+	}
+	t.buckets = t.buckets[expired:]
+}
+
+// burnRate returns how many times faster than the objective allows the
+// error budget burned over the window before now. 1 spends the budget
+// exactly by the end of the SLO period.
+func (t *sloTracker) burnRate(now time.Time, window time.Duration) float64 {
+	cutoff := now.Add(-window)
+	total, failed := 0, 0
+	for i := len(t.buckets) - 1; i >= 0 && t.buckets[i].start.After(cutoff); i-- {
+		total += t.buckets[i].total
+		failed += t.buckets[i].failed
+	}
+	if total == 0 {
+		return 0
+	}
+	return (float64(failed) / float64(total)) / (1 - t.target/100)
+}
+
+// sloStats returns the SLO stats, computing the budget from the lifetime
+// counters. It must be called with a.mux held.
+func (a *Analyzer) sloStats(now time.Time) models.SLOStats {
+	t := a.slo
+	if t.target == 0 {
+		return models.SLOStats{}
+	}
+
+	stats := models.SLOStats{
+		Target:          t.target,
+		SuccessRate:     100,
+		BudgetRemaining: 100,
+		FastBurnRate:    t.burnRate(now, sloFastWindow),
+		SlowBurnRate:    t.burnRate(now, sloSlowWindow),
+	}
+	for level, count := range a.lifetime.levelCounts {
+		stats.Total += count
+		if burnsBudget(level) {
+			stats.Failed += count
+		}
+	}
+	if stats.Total > 0 {
+		errorRatio := float64(stats.Failed) / float64(stats.Total)
+		stats.SuccessRate = 100 * (1 - errorRatio)
+		stats.BudgetRemaining = 100 * (1 - errorRatio/(1-t.target/100))
+	}
+	return stats
+}
+
+// checkSLOBurn alerts when the budget burns too fast over both windows. It
+// must be called with a.mux held.
+func (a *Analyzer) checkSLOBurn(slo models.SLOStats) {
+	if slo.Target == 0 || slo.FastBurnRate < sloBurnThreshold || slo.SlowBurnRate < sloBurnThreshold {
+		return
+	}
+
+	a.sendAlert(alertSLOBurn, models.Alert{
+		Timestamp: a.clock.Now(),
+		Message: fmt.Sprintf("🔥 Error budget burning %.1fx too fast (%.1fx over 1h), %.1f%% of the %g%% SLO budget left",
+			slo.FastBurnRate, slo.SlowBurnRate, slo.BudgetRemaining, slo.Target),
+		Severity: models.SeverityCritical,
+	})
+}
@@ -0,0 +1,68 @@
+// analyzer/stale.go
+// This file contains the detection of an input feed that has gone silent.
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// DefaultStaleAfter is how long without a valid entry before the input is
+// reported as stale
+const DefaultStaleAfter = 30 * time.Second
+
+// staleTracker remembers when the last valid entry arrived. It is guarded
+// by the analyzer's mux.
+type staleTracker struct {
+	after     time.Duration // Silence that raises the alert, 0 disables it
+	lastEntry time.Time     // Arrival of the last valid entry, or the start
+	stale     bool          // Set while the stale alert is raised
+	silentAt  time.Time     // lastEntry when the stale alert fired
+}
+
+// SetStaleAfter sets how long the input may go without a valid entry
+// before a stale feed alert fires, e.g. because the producer died. A
+// second alert reports when input resumes. 0 disables the alert.
+func (a *Analyzer) SetStaleAfter(after time.Duration) error {
+	if after < 0 {
+		return fmt.Errorf("stale input threshold must not be negative, got %s", after)
+	}
+
+	a.staleInput.after = after
+	return nil
+}
+
+// checkStaleInput alerts once when no valid entry has arrived for the
+// stale threshold, and once more when entries arrive again. It is driven by
+// the stats ticker and must be called with a.mux held.
+func (a *Analyzer) checkStaleInput(now time.Time) {
+	s := &a.staleInput
+	if s.after <= 0 {
+		return
+	}
+
+	idle := now.Sub(s.lastEntry)
+	switch {
+	case !s.stale && idle >= s.after:
+		s.stale = true
+		s.silentAt = s.lastEntry
+		a.alertChan <- models.Alert{
+			Timestamp: now,
+			Message:   fmt.Sprintf("⏸️ No input for %s, is the log producer still running?", idle.Round(time.Second)),
+			Severity:  models.SeverityWarning,
+		}
+		if a.debugMode {
+			a.debugLogger.Printf("No valid entry since %s", s.lastEntry.Format(time.RFC3339))
+		}
+	case s.stale && idle < s.after:
+		s.stale = false
+		a.alertChan <- models.Alert{
+			Timestamp: now,
+			Message:   fmt.Sprintf("▶️ Input resumed after %s of silence", s.lastEntry.Sub(s.silentAt).Round(time.Second)),
+			Severity:  models.SeverityInfo,
+		}
+	}
+}
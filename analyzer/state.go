@@ -0,0 +1,201 @@
+// analyzer/state.go
+// This file contains the checkpointing of cumulative stats to a state file, so they survive a restart.
+
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// DefaultStateInterval is how often the state file is written while running
+const DefaultStateInterval = time.Minute
+
+// stateVersion is the format version written to state files. Files of any
+// other version are refused rather than misread.
+const stateVersion = 1
+
+// State is the cumulative stats kept across restarts: the counts since
+// start and the peak rate. The sliding window, rates and pattern weights
+// are not part of it and start afresh.
+type State struct {
+	Version          int                `json:"version"`
+	SavedAt          time.Time          `json:"saved_at"`
+	EntriesProcessed int                `json:"entries_processed"`
+	PeakRate         float64            `json:"peak_rate"`
+	TotalLines       int                `json:"total_lines"`
+	SkippedEntries   int                `json:"skipped_entries"`
+	FilteredEntries  int                `json:"filtered_entries"`
+	RejectCounts     map[string]int     `json:"reject_counts"`
+	Since            time.Time          `json:"since"` // Start of the lifetime totals
+	LevelCounts      map[string]int     `json:"level_counts"`
+	ErrorCounts      map[string]int     `json:"error_counts"`
+	ErrorsByHour     [24]int            `json:"errors_by_hour"`
+	ErrorTypesByHour map[string][24]int `json:"error_types_by_hour"`
+	IPSketch         []byte             `json:"ip_sketch"` // HyperLogLog registers estimating the distinct IPs
+}
+
+// LoadState reads a state file written by SaveState
+func LoadState(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return State{}, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("parse state file %s: %w", path, err)
+	}
+	if state.Version != stateVersion {
+		return State{}, fmt.Errorf("state file %s has version %d, expected %d", path, state.Version, stateVersion)
+	}
+	return state, nil
+}
+
+// SaveState writes state to path atomically: it is written to a temporary
+// file in the same directory, synced and renamed over path, so a crash
+// mid-write leaves the previous state file intact
+func SaveState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetStateFile writes the cumulative stats to the state file at path every
+// interval while running and once more on StopAndDrain. An interval of 0
+// only writes it on shutdown. Load it on the next start with LoadState and
+// RestoreState.
+func (a *Analyzer) SetStateFile(path string, every time.Duration) error {
+	if every < 0 {
+		return fmt.Errorf("state interval must not be negative, got %s", every)
+	}
+
+	a.stateFile = path
+	a.stateInterval = every
+	return nil
+}
+
+// State returns the cumulative stats to checkpoint
+func (a *Analyzer) State() State {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	l := a.lifetime
+	state := State{
+		Version:          stateVersion,
+		SavedAt:          a.clock.Now(),
+		EntriesProcessed: a.stats.EntriesProcessed,
+		PeakRate:         a.stats.PeakRate,
+		TotalLines:       a.totalLines,
+		SkippedEntries:   a.skippedEntries,
+		FilteredEntries:  a.filteredEntries,
+		RejectCounts:     make(map[string]int, len(a.rejectCounts)),
+		Since:            l.since,
+		LevelCounts:      make(map[string]int, len(l.levelCounts)),
+		ErrorCounts:      make(map[string]int, len(l.errorCounts)),
+		ErrorsByHour:     l.errorsByHour,
+		ErrorTypesByHour: make(map[string][24]int, len(l.errorTypesByHour)),
+		IPSketch:         append([]byte(nil), l.ips.registers[:]...),
+	}
+	for reason, count := range a.rejectCounts {
+		state.RejectCounts[reason] = count
+	}
+	for level, count := range l.levelCounts {
+		state.LevelCounts[level] = count
+	}
+	for errType, count := range l.errorCounts {
+		state.ErrorCounts[errType] = count
+	}
+	for errType, counts := range l.errorTypesByHour {
+		state.ErrorTypesByHour[errType] = counts
+	}
+	return state
+}
+
+// RestoreState continues the cumulative stats from a saved state. Call it
+// before Start and after SetClock and SetRateBucket, which reset them. The
+// lifetime rate series starts afresh.
+func (a *Analyzer) RestoreState(state State) error {
+	if state.Version != stateVersion {
+		return fmt.Errorf("state has version %d, expected %d", state.Version, stateVersion)
+	}
+	if state.IPSketch != nil && len(state.IPSketch) != hllRegisters {
+		return fmt.Errorf("IP sketch has %d registers, expected %d", len(state.IPSketch), hllRegisters)
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.stats.EntriesProcessed = state.EntriesProcessed
+	a.stats.PeakRate = state.PeakRate
+	a.totalLines = state.TotalLines
+	a.skippedEntries = state.SkippedEntries
+	a.filteredEntries = state.FilteredEntries
+	for reason, count := range state.RejectCounts {
+		a.rejectCounts[reason] = count
+	}
+
+	since := state.Since
+	if since.IsZero() {
+		since = a.lifetime.since
+	}
+	l := newLifetimeAggregator(since, a.bucketInterval)
+	for level, count := range state.LevelCounts {
+		l.levelCounts[level] = count
+	}
+	for errType, count := range state.ErrorCounts {
+		l.errorCounts[errType] = count
+	}
+	l.errorsByHour = state.ErrorsByHour
+	for errType, counts := range state.ErrorTypesByHour {
+		l.errorTypesByHour[errType] = counts
+	}
+	copy(l.ips.registers[:], state.IPSketch)
+	a.lifetime = l
+	return nil
+}
+
+// saveState checkpoints the cumulative stats to the state file, raising a
+// warning alert if it cannot be written
+func (a *Analyzer) saveState() {
+	if a.stateFile == "" {
+		return
+	}
+
+	if err := SaveState(a.stateFile, a.State()); err != nil {
+		a.sendAlert(alertStateSave, models.Alert{
+			Timestamp: a.clock.Now(),
+			Message:   fmt.Sprintf("⚠️ Failed to save state to %s: %v", a.stateFile, err),
+			Severity:  models.SeverityWarning,
+		})
+		return
+	}
+	if a.debugMode {
+		a.debugLogger.Printf("Saved state to %s", a.stateFile)
+	}
+}
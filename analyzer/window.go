@@ -5,12 +5,36 @@ package analyzer
 
 import (
 	"container/list"
+	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"log_analyzer/models"
 )
 
+// TimeReference selects what "now" is for window expiry and rates
+type TimeReference string
+
+const (
+	// TimeWall measures the window back from the wall clock, for live traffic
+	TimeWall TimeReference = "wall"
+	// TimeLog measures the window back from the newest entry's own
+	// timestamp, so historical logs read from a file get correct rates
+	TimeLog TimeReference = "log"
+)
+
+// ParseTimeReference validates a time reference name
+func ParseTimeReference(name string) (TimeReference, error) {
+	switch ref := TimeReference(name); ref {
+	case TimeWall, TimeLog:
+		return ref, nil
+	default:
+		return "", fmt.Errorf("unknown time reference %q", name)
+	}
+}
+
 // SlidingWindow maintains a time-based window of log entries
 type SlidingWindow struct {
 	entries       *list.List
@@ -18,22 +42,41 @@ type SlidingWindow struct {
 	errorsByType  map[string]*list.List
 	duration      time.Duration
 	totalCount    int
-	levelCounts   map[string]int
-	errorCounts   map[string]int
+	levelCounts   *Aggregator
+	errorCounts   *Aggregator
+	ipCounts      *Aggregator
+	groupBy       string        // Dimension counted in groupCounts, see SetGroupBy
+	groupCounts   *Aggregator   // Nil unless a group-by dimension is set
+	aggregators   []*Aggregator // All of the above, updated as entries enter and leave
+	compact       bool          // Drop message text from stored entries to save memory
 	mux           sync.RWMutex
 	analyzer      *Analyzer
+	clock         Clock
+	timeRef       TimeReference
+	latest        time.Time // Newest entry timestamp, the reference time with TimeLog
+	started       time.Time // Reference time of the first entry, see rateSpan
 }
 
 // NewSlidingWindow creates a new sliding window with the specified duration
 func NewSlidingWindow(durationSec int) *SlidingWindow {
-	return &SlidingWindow{
+	w := &SlidingWindow{
 		entries:       list.New(),
 		entriesByType: make(map[string]*list.List),
 		errorsByType:  make(map[string]*list.List),
 		duration:      time.Duration(durationSec) * time.Second,
-		levelCounts:   make(map[string]int),
-		errorCounts:   make(map[string]int),
+		levelCounts:   NewAggregator(SelectLevel),
+		errorCounts:   NewAggregator(SelectErrorType),
+		ipCounts:      NewAggregator(SelectIP),
+		clock:         RealClock,
+		timeRef:       TimeWall,
 	}
+
+	// Levels and error types that left the window stay listed with 0
+	w.levelCounts.keepEmpty = true
+	w.errorCounts.keepEmpty = true
+
+	w.aggregators = []*Aggregator{w.levelCounts, w.errorCounts, w.ipCounts}
+	return w
 }
 
 // SetAnalyzer sets the analyzer reference
@@ -41,13 +84,91 @@ func (w *SlidingWindow) SetAnalyzer(analyzer *Analyzer) {
 	w.analyzer = analyzer
 }
 
+// SetClock replaces the clock entries expire against. Call it before the
+// first Add.
+func (w *SlidingWindow) SetClock(clock Clock) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.clock = clock
+}
+
+// SetTimeReference sets whether expiry and rates are measured back from
+// the wall clock or from the newest entry's timestamp
+func (w *SlidingWindow) SetTimeReference(ref TimeReference) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.timeRef = ref
+}
+
+// now returns the reference time the window is measured back from. The
+// caller must hold the lock.
+func (w *SlidingWindow) now() time.Time {
+	if w.timeRef == TimeLog && !w.latest.IsZero() {
+		return w.latest
+	}
+	return w.clock.Now()
+}
+
+// SetGroupBy counts the entries in the window by a dimension for
+// GetTopGroups: one of the Group* names or a field extracted by a named
+// group of the line format, e.g. "status". An empty dimension stops
+// counting. Call it before the first Add.
+func (w *SlidingWindow) SetGroupBy(dimension string) error {
+	var groups *Aggregator
+	if dimension != "" {
+		selector, err := SelectorFor(dimension)
+		if err != nil {
+			return err
+		}
+		groups = NewAggregator(selector)
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.groupBy = dimension
+	w.groupCounts = groups
+	w.aggregators = []*Aggregator{w.levelCounts, w.errorCounts, w.ipCounts}
+	if groups != nil {
+		w.aggregators = append(w.aggregators, groups)
+	}
+	return nil
+}
+
+// SetCompact enables lean storage, where entries keep only the fields
+// needed for counts and rates (timestamp, level, IP, error type, duration
+// and the group-by field)
+func (w *SlidingWindow) SetCompact(compact bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.compact = compact
+}
+
 // Add adds a log entry to the window
 func (w *SlidingWindow) Add(entry models.LogEntry) {
 	w.mux.Lock()
 	defer w.mux.Unlock()
 
-	now := time.Now()
-	cutoff := now.Add(-w.duration)
+	if w.compact {
+		entry.OriginalLog = ""
+		entry.Message = ""
+		if value, ok := entry.Fields[w.groupBy]; !ok {
+			entry.Fields = nil
+		} else if len(entry.Fields) > 1 {
+			entry.Fields = map[string]string{w.groupBy: value}
+		}
+	}
+
+	if entry.Timestamp.After(w.latest) {
+		w.latest = entry.Timestamp
+	}
+	cutoff := w.now().Add(-w.duration)
+	if w.started.IsZero() {
+		w.started = w.now()
+	}
 
 	// Remove expired entries
 	w.removeExpiredEntries(cutoff)
@@ -56,8 +177,10 @@ func (w *SlidingWindow) Add(entry models.LogEntry) {
 	w.entries.PushBack(entry)
 	w.totalCount++
 
-	// Update level counts
-	w.levelCounts[entry.Level]++
+	// Update the counts by level, error type, IP and group-by dimension
+	for _, aggregator := range w.aggregators {
+		aggregator.Add(entry)
+	}
 
 	// Update type-specific lists
 	if _, ok := w.entriesByType[entry.Level]; !ok {
@@ -65,10 +188,8 @@ func (w *SlidingWindow) Add(entry models.LogEntry) {
 	}
 	w.entriesByType[entry.Level].PushBack(entry)
 
-	// Update error counts if applicable
+	// Update error-specific lists if applicable
 	if entry.Level == "ERROR" && entry.ErrorType != "" {
-		w.errorCounts[entry.ErrorType]++
-		
 		if _, ok := w.errorsByType[entry.ErrorType]; !ok {
 			w.errorsByType[entry.ErrorType] = list.New()
 		}
@@ -86,7 +207,7 @@ func (w *SlidingWindow) SetDuration(durationSec int) {
 
 	// If the window is shrinking, remove older entries
 	if w.duration < oldDuration {
-		w.removeExpiredEntries(time.Now().Add(-w.duration))
+		w.removeExpiredEntries(w.now().Add(-w.duration))
 	}
 }
 
@@ -95,19 +216,96 @@ func (w *SlidingWindow) GetStats() (int, map[string]int, map[string]int) {
 	w.mux.RLock()
 	defer w.mux.RUnlock()
 
-	// Make copies of the maps
-	levelCounts := make(map[string]int)
-	errorCounts := make(map[string]int)
+	return w.totalCount, w.levelCounts.Counts(), w.errorCounts.Counts()
+}
+
+// WindowSnapshot is a point-in-time copy of the window contents, for debugging
+type WindowSnapshot struct {
+	Duration    time.Duration
+	Total       int
+	LevelCounts map[string]int
+	ErrorCounts map[string]int
+	Oldest      time.Time // Zero if the window is empty
+	Newest      time.Time
+}
+
+// Snapshot returns a copy of the window contents. Entries that expired since
+// the last Add are still included, as pruning only happens on Add.
+func (w *SlidingWindow) Snapshot() WindowSnapshot {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	snap := WindowSnapshot{
+		Duration:    w.duration,
+		Total:       w.totalCount,
+		LevelCounts: w.levelCounts.Counts(),
+		ErrorCounts: w.errorCounts.Counts(),
+	}
+
+	// Entries can arrive slightly out of order, so scan rather than trust
+	// the ends of the list
+	for e := w.entries.Front(); e != nil; e = e.Next() {
+		ts := e.Value.(models.LogEntry).Timestamp
+		if snap.Oldest.IsZero() || ts.Before(snap.Oldest) {
+			snap.Oldest = ts
+		}
+		if ts.After(snap.Newest) {
+			snap.Newest = ts
+		}
+	}
+	return snap
+}
+
+// GetTopIPs returns the n source IPs with the most entries in the window
+func (w *SlidingWindow) GetTopIPs(n int) []models.IPCount {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
 
-	for k, v := range w.levelCounts {
-		levelCounts[k] = v
+	top := w.ipCounts.Top(n)
+	result := make([]models.IPCount, len(top))
+	for i, ip := range top {
+		result[i] = models.IPCount{IP: ip.Value, Count: ip.Count}
 	}
+	return result
+}
+
+// GetTopGroups returns the n values of the group-by dimension with the most
+// entries in the window, or nil if none is set, see SetGroupBy
+func (w *SlidingWindow) GetTopGroups(n int) []models.FieldCount {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
 
-	for k, v := range w.errorCounts {
-		errorCounts[k] = v
+	if w.groupCounts == nil {
+		return nil
 	}
+	return w.groupCounts.Top(n)
+}
 
-	return w.totalCount, levelCounts, errorCounts
+// rateSpan returns the number of seconds a rate over the last N seconds is
+// divided by: N, or less during the first N seconds of input, so rates are
+// not understated at startup. It is at least one second, so a handful of
+// entries right at the start do not read as a spike. The caller must hold
+// the lock.
+func (w *SlidingWindow) rateSpan(seconds int) float64 {
+	span := time.Duration(seconds) * time.Second
+	if !w.started.IsZero() {
+		elapsed := w.now().Sub(w.started)
+		if elapsed < time.Second {
+			elapsed = time.Second
+		}
+		if elapsed < span {
+			span = elapsed
+		}
+	}
+	return span.Seconds()
+}
+
+// GetUniqueIPs returns the number of distinct source IPs in the window
+func (w *SlidingWindow) GetUniqueIPs() int {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	return w.ipCounts.Len()
 }
 
 // GetErrorRate calculates the rate of a specific error type over the last N seconds
@@ -116,7 +314,30 @@ func (w *SlidingWindow) GetErrorRate(errorType string, seconds int) float64 {
 	defer w.mux.RUnlock()
 
 	if list, ok := w.errorsByType[errorType]; ok {
-		cutoff := time.Now().Add(-time.Duration(seconds) * time.Second)
+		cutoff := w.now().Add(-time.Duration(seconds) * time.Second)
+		count := 0
+
+		for e := list.Back(); e != nil; e = e.Prev() {
+			entry := e.Value.(models.LogEntry)
+			if entry.Timestamp.Before(cutoff) {
+				break
+			}
+			count++
+		}
+
+		return float64(count) / w.rateSpan(seconds)
+	}
+
+	return 0
+}
+
+// GetLevelRate calculates the rate of entries with the given level over the last N seconds
+func (w *SlidingWindow) GetLevelRate(level string, seconds int) float64 {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	if list, ok := w.entriesByType[level]; ok {
+		cutoff := w.now().Add(-time.Duration(seconds) * time.Second)
 		count := 0
 
 		for e := list.Back(); e != nil; e = e.Prev() {
@@ -127,22 +348,24 @@ func (w *SlidingWindow) GetErrorRate(errorType string, seconds int) float64 {
 			count++
 		}
 
-		return float64(count) / float64(seconds)
+		return float64(count) / w.rateSpan(seconds)
 	}
 
 	return 0
 }
 
-// GetErrorChange calculates the percentage change in error rate
-func (w *SlidingWindow) GetErrorChange(errorType string, recentSec, prevSec int) float64 {
+// GetErrorChange calculates the percentage change in error rate. Changes
+// resting on fewer than minCount recent errors are reported as 0, so a
+// handful of errors in a quiet period is not taken for a trend.
+func (w *SlidingWindow) GetErrorChange(errorType string, recentSec, prevSec, minCount int) float64 {
 	w.mux.RLock()
 	defer w.mux.RUnlock()
 
 	if list, ok := w.errorsByType[errorType]; ok {
-		now := time.Now()
+		now := w.now()
 		recentCutoff := now.Add(-time.Duration(recentSec) * time.Second)
 		prevCutoff := recentCutoff.Add(-time.Duration(prevSec) * time.Second)
-		
+
 		recentCount := 0
 		prevCount := 0
 
@@ -157,65 +380,153 @@ func (w *SlidingWindow) GetErrorChange(errorType string, recentSec, prevSec int)
 			}
 		}
 
-		// Calculate percentage change
-		if prevCount == 0 {
-			if recentCount > 0 {
-				return 100.0 // 100% increase (from 0 to something)
-			}
+		if recentCount < max(minCount, 1) {
 			return 0.0
 		}
-
-		return 100.0 * float64(recentCount-prevCount) / float64(prevCount)
+		return percentChange(recentCount, prevCount)
 	}
 
 	return 0.0
 }
 
-// removeExpiredEntries removes entries older than the cutoff time
-func (w *SlidingWindow) removeExpiredEntries(cutoff time.Time) {
-	// Remove from main list and update counts
-	for e := w.entries.Front(); e != nil; {
+// percentChange returns the percentage change from prev to recent. An
+// empty previous period counts as one error, so 0 to 1 is no change and
+// the change grows with the recent count instead of being a flat 100%.
+func percentChange(recent, prev int) float64 {
+	baseline := max(prev, 1)
+	return 100.0 * float64(recent-baseline) / float64(baseline)
+}
+
+// GetDurationPercentiles returns the requested percentiles (0-100) of the
+// durations carried by entries still inside the window, along with the
+// number of entries that had a duration
+func (w *SlidingWindow) GetDurationPercentiles(percentiles ...float64) ([]float64, int) {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	cutoff := w.now().Add(-w.duration)
+	var durations []float64
+	for e := w.entries.Back(); e != nil; e = e.Prev() {
 		entry := e.Value.(models.LogEntry)
 		if entry.Timestamp.Before(cutoff) {
-			next := e.Next()
-			w.entries.Remove(e)
-			w.totalCount--
-			w.levelCounts[entry.Level]--
-			
-			// Remove from level-specific list
-			if list, ok := w.entriesByType[entry.Level]; ok {
-				for le := list.Front(); le != nil; {
-					lEntry := le.Value.(models.LogEntry)
-					if lEntry.Timestamp.Equal(entry.Timestamp) {
-						nextLe := le.Next()
-						list.Remove(le)
-						le = nextLe
-						break
-					}
-					le = le.Next()
-				}
-			}
-			
-			// Remove from error-specific list if applicable
-			if entry.Level == "ERROR" && entry.ErrorType != "" {
-				w.errorCounts[entry.ErrorType]--
-				if list, ok := w.errorsByType[entry.ErrorType]; ok {
-					for le := list.Front(); le != nil; {
-						lEntry := le.Value.(models.LogEntry)
-						if lEntry.Timestamp.Equal(entry.Timestamp) {
-							nextLe := le.Next()
-							list.Remove(le)
-							le = nextLe
-							break
-						}
-						le = le.Next()
-					}
-				}
-			}
-			
-			e = next
+			break
+		}
+		if entry.HasDuration {
+			durations = append(durations, entry.DurationMs)
+		}
+	}
+
+	result := make([]float64, len(percentiles))
+	if len(durations) == 0 {
+		return result, 0
+	}
+
+	// Nearest-rank percentiles over the sorted durations
+	sort.Float64s(durations)
+	for i, p := range percentiles {
+		rank := int(math.Ceil(p/100*float64(len(durations)))) - 1
+		if rank < 0 {
+			rank = 0
+		} else if rank >= len(durations) {
+			rank = len(durations) - 1
+		}
+		result[i] = durations[rank]
+	}
+
+	return result, len(durations)
+}
+
+// IPErrorChange describes how a single IP's errors changed between two periods
+type IPErrorChange struct {
+	IP          string
+	RecentCount int
+	RecentRate  float64 // errors/sec over the recent period
+	Share       float64 // fraction of all recent errors coming from this IP
+	Change      float64 // percentage change from the previous period
+}
+
+// GetIPErrorChanges calculates, for each IP with recent ERROR entries, its
+// error rate and share over the last recentSec seconds and the percentage
+// change compared to the prevSec seconds before that
+func (w *SlidingWindow) GetIPErrorChanges(recentSec, prevSec int) []IPErrorChange {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	list, ok := w.entriesByType["ERROR"]
+	if !ok {
+		return nil
+	}
+
+	now := w.now()
+	recentCutoff := now.Add(-time.Duration(recentSec) * time.Second)
+	prevCutoff := recentCutoff.Add(-time.Duration(prevSec) * time.Second)
+
+	recentCounts := make(map[string]int)
+	prevCounts := make(map[string]int)
+	totalRecent := 0
+
+	for e := list.Back(); e != nil; e = e.Prev() {
+		entry := e.Value.(models.LogEntry)
+		if entry.IP == "" {
+			continue
+		}
+		if entry.Timestamp.After(recentCutoff) {
+			recentCounts[entry.IP]++
+			totalRecent++
+		} else if entry.Timestamp.After(prevCutoff) {
+			prevCounts[entry.IP]++
 		} else {
+			break
+		}
+	}
+
+	result := make([]IPErrorChange, 0, len(recentCounts))
+	for ip, recentCount := range recentCounts {
+		// Same percentage change semantics as GetErrorChange
+		change := percentChange(recentCount, prevCounts[ip])
+
+		result = append(result, IPErrorChange{
+			IP:          ip,
+			RecentCount: recentCount,
+			RecentRate:  float64(recentCount) / w.rateSpan(recentSec),
+			Share:       float64(recentCount) / float64(totalRecent),
+			Change:      change,
+		})
+	}
+
+	return result
+}
+
+// removeExpiredEntries removes entries older than the cutoff time.
+//
+// Entries are only ever appended to the back of each list and removed from
+// the front of the main list, so every per-level and per-error-type list is
+// the same sequence filtered by type. The expired entry at the front of the
+// main list is therefore always the front of its type lists too, which makes
+// each removal O(1) instead of a scan.
+func (w *SlidingWindow) removeExpiredEntries(cutoff time.Time) {
+	for e := w.entries.Front(); e != nil; e = w.entries.Front() {
+		entry := e.Value.(models.LogEntry)
+		if !entry.Timestamp.Before(cutoff) {
 			break // Entries are sorted by time, so we can stop once we hit a non-expired entry
 		}
+
+		w.entries.Remove(e)
+		w.totalCount--
+		for _, aggregator := range w.aggregators {
+			aggregator.Remove(entry)
+		}
+
+		// Remove from level-specific list
+		if list, ok := w.entriesByType[entry.Level]; ok && list.Len() > 0 {
+			list.Remove(list.Front())
+		}
+
+		// Remove from error-specific list if applicable
+		if entry.Level == "ERROR" && entry.ErrorType != "" {
+			if list, ok := w.errorsByType[entry.ErrorType]; ok && list.Len() > 0 {
+				list.Remove(list.Front())
+			}
+		}
 	}
 }
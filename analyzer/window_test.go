@@ -0,0 +1,198 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// newTestWindow returns a window of durationSec seconds on a fake clock
+func newTestWindow(durationSec int) (*SlidingWindow, *fakeClock) {
+	clock := newFakeClock()
+	w := NewSlidingWindow(durationSec)
+	w.SetClock(clock)
+	return w, clock
+}
+
+// testEntry returns a valid entry logged at t
+func testEntry(t time.Time, level, errorType, ip string) models.LogEntry {
+	return models.LogEntry{
+		Timestamp:   t,
+		Level:       level,
+		IP:          ip,
+		ErrorType:   errorType,
+		Message:     "Error 500 - " + errorType,
+		OriginalLog: "original line",
+		IsValid:     true,
+	}
+}
+
+func TestWindowExpiresOldEntries(t *testing.T) {
+	w, clock := newTestWindow(10)
+
+	w.Add(testEntry(clock.Now(), "ERROR", "Timeout", "10.0.0.1"))
+	clock.Advance(5 * time.Second)
+	w.Add(testEntry(clock.Now(), "INFO", "", "10.0.0.2"))
+
+	total, levels, errors := w.GetStats()
+	if total != 2 || levels["ERROR"] != 1 || errors["Timeout"] != 1 {
+		t.Fatalf("before expiry: total %d, levels %v, errors %v", total, levels, errors)
+	}
+
+	// The first entry is now 11s old, past the 10s window
+	clock.Advance(6 * time.Second)
+	w.Add(testEntry(clock.Now(), "INFO", "", "10.0.0.2"))
+
+	total, levels, errors = w.GetStats()
+	if total != 2 || levels["ERROR"] != 0 || levels["INFO"] != 2 || errors["Timeout"] != 0 {
+		t.Errorf("after expiry: total %d, levels %v, errors %v", total, levels, errors)
+	}
+	if got := w.GetUniqueIPs(); got != 1 {
+		t.Errorf("unique IPs after expiry = %d, want 1", got)
+	}
+}
+
+func TestCompactWindowMatchesFullWindow(t *testing.T) {
+	full, fullClock := newTestWindow(30)
+	compact, compactClock := newTestWindow(30)
+	compact.SetCompact(true)
+	for _, w := range []*SlidingWindow{full, compact} {
+		if err := w.SetGroupBy("service"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	levels := []string{"INFO", "WARNING", "ERROR", "DEBUG"}
+	errorTypes := []string{"Timeout", "Connection Refused", "Disk Full"}
+	services := []string{"api", "auth", "db"}
+
+	// Several entries a second for longer than the window, so both windows
+	// also expire entries
+	for i := 0; i < 400; i++ {
+		entry := testEntry(fullClock.Now(), levels[i%len(levels)], "", fmt.Sprintf("10.0.0.%d", i%7))
+		if entry.Level == "ERROR" {
+			entry.ErrorType = errorTypes[i%len(errorTypes)]
+		}
+		entry.Fields = map[string]string{"service": services[i%len(services)], "request_id": fmt.Sprint(i)}
+		entry.DurationMs = float64(i % 250)
+		entry.HasDuration = true
+
+		full.Add(entry)
+		compact.Add(entry)
+		fullClock.Advance(150 * time.Millisecond)
+		compactClock.Advance(150 * time.Millisecond)
+	}
+
+	fullTotal, fullLevels, fullErrors := full.GetStats()
+	compactTotal, compactLevels, compactErrors := compact.GetStats()
+	if fullTotal != compactTotal || !reflect.DeepEqual(fullLevels, compactLevels) || !reflect.DeepEqual(fullErrors, compactErrors) {
+		t.Errorf("counts differ: full %d %v %v, compact %d %v %v",
+			fullTotal, fullLevels, fullErrors, compactTotal, compactLevels, compactErrors)
+	}
+	if fullTotal == 0 || fullTotal == 400 {
+		t.Fatalf("window holds %d entries, expected some to have expired", fullTotal)
+	}
+
+	for _, level := range levels {
+		if f, c := full.GetLevelRate(level, 10), compact.GetLevelRate(level, 10); f != c {
+			t.Errorf("%s rate: full %g, compact %g", level, f, c)
+		}
+	}
+	for _, errType := range errorTypes {
+		if f, c := full.GetErrorRate(errType, 10), compact.GetErrorRate(errType, 10); f != c {
+			t.Errorf("%s rate: full %g, compact %g", errType, f, c)
+		}
+		if f, c := full.GetErrorChange(errType, 5, 5, 1), compact.GetErrorChange(errType, 5, 5, 1); f != c {
+			t.Errorf("%s change: full %g, compact %g", errType, f, c)
+		}
+	}
+
+	if f, c := full.GetTopIPs(5), compact.GetTopIPs(5); !reflect.DeepEqual(f, c) {
+		t.Errorf("top IPs: full %v, compact %v", f, c)
+	}
+	if f, c := full.GetTopGroups(5), compact.GetTopGroups(5); !reflect.DeepEqual(f, c) {
+		t.Errorf("top services: full %v, compact %v", f, c)
+	}
+	if f, c := full.GetUniqueIPs(), compact.GetUniqueIPs(); f != c {
+		t.Errorf("unique IPs: full %d, compact %d", f, c)
+	}
+	fullP, fullN := full.GetDurationPercentiles(50, 95, 99)
+	compactP, compactN := compact.GetDurationPercentiles(50, 95, 99)
+	if fullN != compactN || !reflect.DeepEqual(fullP, compactP) {
+		t.Errorf("duration percentiles: full %v of %d, compact %v of %d", fullP, fullN, compactP, compactN)
+	}
+
+	// The compact window kept only what the counts need
+	stored := compact.entries.Back().Value.(models.LogEntry)
+	if stored.Message != "" || stored.OriginalLog != "" || len(stored.Fields) != 1 {
+		t.Errorf("compact window stored message %q, line %q and fields %v", stored.Message, stored.OriginalLog, stored.Fields)
+	}
+}
+
+func TestWindowRatesAtColdStart(t *testing.T) {
+	w, clock := newTestWindow(60)
+
+	for i := 0; i < 10; i++ {
+		w.Add(testEntry(clock.Now(), "ERROR", "Timeout", "10.0.0.1"))
+	}
+
+	// Right at the start the span is one second, not the whole minute
+	if got := w.GetErrorRate("Timeout", 60); got != 10 {
+		t.Errorf("error rate at start = %g, want 10", got)
+	}
+	if changes := w.GetIPErrorChanges(15, 15); len(changes) != 1 || changes[0].RecentRate != 10 {
+		t.Errorf("IP error changes at start = %+v, want a rate of 10", changes)
+	}
+
+	clock.Advance(2 * time.Second)
+	for i := 0; i < 10; i++ {
+		w.Add(testEntry(clock.Now(), "ERROR", "Timeout", "10.0.0.1"))
+	}
+
+	// 20 errors over the 2 seconds seen so far
+	if got := w.GetErrorRate("Timeout", 60); got != 10 {
+		t.Errorf("error rate after 2s = %g, want 10", got)
+	}
+	if got := w.GetLevelRate("ERROR", 60); got != 10 {
+		t.Errorf("ERROR rate after 2s = %g, want 10", got)
+	}
+	// Shorter periods are not affected once they have elapsed
+	if got := w.GetLevelRate("ERROR", 1); got != 10 {
+		t.Errorf("ERROR rate over 1s = %g, want 10", got)
+	}
+
+	// Once the window has filled, rates are over the full period
+	clock.Advance(58 * time.Second)
+	w.Add(testEntry(clock.Now(), "ERROR", "Timeout", "10.0.0.1"))
+	if got, want := w.GetErrorRate("Timeout", 60), 21.0/60; got != want {
+		t.Errorf("error rate after 60s = %g, want %g", got, want)
+	}
+}
+
+func BenchmarkRemoveExpiredEntries(b *testing.B) {
+	levels := []string{"INFO", "WARNING", "ERROR", "DEBUG"}
+	errorTypes := []string{"Timeout", "Connection Refused", "Disk Full"}
+
+	for _, size := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("entries=%d", size), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				w, clock := newTestWindow(3600)
+				for i := 0; i < size; i++ {
+					entry := testEntry(clock.Now(), levels[i%len(levels)], "", fmt.Sprintf("10.0.%d.%d", i/256%256, i%256))
+					if entry.Level == "ERROR" {
+						entry.ErrorType = errorTypes[i%len(errorTypes)]
+					}
+					w.Add(entry)
+					clock.Advance(time.Millisecond)
+				}
+				b.StartTimer()
+
+				w.removeExpiredEntries(clock.Now())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// cmd/log_generator/main.go - Writes a synthetic log stream to stdout, for
+// stress-testing the analyzer without the shell generators.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"log_analyzer/generator"
+)
+
+func main() {
+	defaults := generator.DefaultOptions()
+	rate := flag.Float64("rate", defaults.Rate, "Entries per second between bursts")
+	errorRatio := flag.Float64("error-ratio", defaults.ErrorRatio, "Share (0-1) of entries logged as ERROR; the rest are split between INFO and DEBUG")
+	burstSize := flag.Int("burst-size", defaults.BurstSize, "Extra entries written at once every -burst-every (0 disables bursts)")
+	burstEvery := flag.Duration("burst-every", defaults.BurstEvery, "Interval between bursts")
+	count := flag.Int("count", defaults.Count, "Stop after this many entries (0 runs until interrupted)")
+	seed := flag.Int64("seed", defaults.Seed, "Random seed; the same seed gives the same sequence of levels, messages and IPs")
+	start := flag.String("start", "", "Use synthetic timestamps starting at this RFC3339 time and write as fast as possible, for reproducible output")
+	flag.Parse()
+
+	opts := defaults
+	opts.Rate = *rate
+	opts.ErrorRatio = *errorRatio
+	opts.BurstSize = *burstSize
+	opts.BurstEvery = *burstEvery
+	opts.Count = *count
+	opts.Seed = *seed
+	if *start != "" {
+		startTime, err := time.Parse(time.RFC3339, *start)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -start: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Start = startTime
+	}
+
+	gen, err := generator.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if _, err := gen.Run(ctx, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write: %v\n", err)
+		os.Exit(1)
+	}
+}
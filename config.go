@@ -0,0 +1,124 @@
+// config.go - Loading flag defaults from a JSON config file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// fileConfig holds the settings read from a -config file, keyed by flag
+// name without the dash, e.g. {"top-n": 5, "output": "json"}. Durations
+// are strings as on the command line; a repeatable flag takes an array.
+type fileConfig map[string]json.RawMessage
+
+// loadConfig reads a JSON config file
+func loadConfig(path string) (fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// apply sets the flags in fs from the config, skipping any given on the
+// command line so those override the file. It returns the keys that match
+// no flag, which the caller warns about rather than failing.
+func (c fileConfig) apply(fs *flag.FlagSet) (unknown []string, err error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	// Apply in a fixed order so errors are reproducible
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "config" || fs.Lookup(key) == nil {
+			unknown = append(unknown, key)
+			continue
+		}
+		if explicit[key] {
+			continue
+		}
+		values, err := configValues(c[key])
+		if err != nil {
+			return unknown, fmt.Errorf("%s: %w", key, err)
+		}
+		for _, value := range values {
+			if err := fs.Set(key, value); err != nil {
+				return unknown, fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+			}
+		}
+	}
+	return unknown, nil
+}
+
+// configValues converts a config value into the flag value strings it
+// stands for: one for a scalar, one per element for an array
+func configValues(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+
+	s, err := configScalar(value)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// configScalar formats a string, number or boolean as a flag value
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or array, got %s", jsonKind(v))
+	}
+}
+
+// jsonKind names the JSON type of a decoded value for error messages
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "nested array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
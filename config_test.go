@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestConfigApply(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	topN := fs.Int("top-n", 3, "")
+	output := fs.String("output", "text", "")
+	var layouts stringList
+	fs.Var(&layouts, "time-layout", "")
+	if err := fs.Parse([]string{"-output", "oneline"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg fileConfig
+	data := `{"top-n": 5, "output": "json", "time-layout": ["2006-01-02", "15:04"], "window": 90}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := cfg.apply(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *topN != 5 {
+		t.Errorf("top-n = %d, want 5 from the file", *topN)
+	}
+	if *output != "oneline" {
+		t.Errorf("output = %q, want the command line's oneline", *output)
+	}
+	if want := []string{"2006-01-02", "15:04"}; !reflect.DeepEqual([]string(layouts), want) {
+		t.Errorf("time-layout = %v, want %v", layouts, want)
+	}
+	if want := []string{"window"}; !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown keys %v, want %v", unknown, want)
+	}
+}
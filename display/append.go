@@ -0,0 +1,59 @@
+// display/append.go - Append-only output that never clears the terminal.
+
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetNoClear stops the display from clearing the terminal every second.
+// Each update then prints only the report lines that changed since the
+// previous one, which keeps tmux panes and redirected output readable.
+func (d *Display) SetNoClear(noClear bool) {
+	d.noClear = noClear
+	if noClear {
+		d.clearScreenFn = func() {}
+	} else {
+		d.clearScreenFn = clearScreen
+	}
+}
+
+// printChanges prints the lines of report that were not in the previous
+// report, prefixed with the update time. Headers, separators and section
+// titles are left out since they carry no data on their own.
+func (d *Display) printChanges(updated time.Time, report string) {
+	lines := make(map[string]bool)
+	prefix := d.inLocation(updated, time.Local).Format("15:04:05")
+
+	d.outMux.Lock()
+	defer d.outMux.Unlock()
+
+	for _, line := range strings.Split(report, "\n") {
+		line = strings.TrimSpace(line)
+		if !isDataLine(line) {
+			continue
+		}
+
+		lines[line] = true
+		if !d.lastLines[line] {
+			fmt.Printf("%s %s\n", prefix, line)
+		}
+	}
+
+	d.lastLines = lines
+}
+
+// isDataLine reports whether a report line holds a value worth printing
+func isDataLine(line string) bool {
+	switch {
+	case line == "", strings.HasSuffix(line, ":"):
+		return false
+	case strings.HasPrefix(line, "━"), strings.HasPrefix(line, "Log Analysis Report"),
+		strings.HasPrefix(line, "Press Ctrl+C"):
+		return false
+	default:
+		return true
+	}
+}
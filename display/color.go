@@ -0,0 +1,92 @@
+// display/color.go - ANSI colors for the terminal report.
+
+package display
+
+import (
+	"fmt"
+	"os"
+
+	"log_analyzer/models"
+)
+
+// ColorMode selects when the report is colorized
+type ColorMode string
+
+const (
+	// ColorAuto colorizes only when stdout is a terminal
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colorizes even when output is redirected
+	ColorAlways ColorMode = "always"
+	// ColorNever never emits color codes
+	ColorNever ColorMode = "never"
+)
+
+// ANSI escape sequences used by the report
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiBold   = "\033[1m"
+)
+
+// ParseColorMode validates a color mode name
+func ParseColorMode(name string) (ColorMode, error) {
+	switch mode := ColorMode(name); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown color mode %q", name)
+	}
+}
+
+// SetColor enables or disables colors according to mode
+func (d *Display) SetColor(mode ColorMode) {
+	switch mode {
+	case ColorAlways:
+		d.color = true
+	case ColorAuto:
+		d.color = isTerminal(os.Stdout)
+	default:
+		d.color = false
+	}
+}
+
+// colorize wraps text in the given color, unless colors are disabled
+func (d *Display) colorize(text, color string) string {
+	if !d.color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// levelColor picks the color for a level line by the level's severity
+func levelColor(level string) string {
+	switch models.LevelSeverity(level) {
+	case models.SeverityCritical, models.SeverityError:
+		return ansiRed
+	case models.SeverityWarning:
+		return ansiYellow
+	case models.SeverityInfo:
+		return ansiGreen
+	default:
+		return ""
+	}
+}
+
+// alertColor highlights alerts, in red for critical ones and yellow otherwise
+func alertColor(severity models.Severity) string {
+	if severity >= models.SeverityCritical {
+		return ansiBold + ansiRed
+	}
+	return ansiBold + ansiYellow
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
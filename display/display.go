@@ -5,6 +5,8 @@ package display
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"log_analyzer/models"
@@ -12,64 +14,198 @@ import (
 
 // Display handles rendering the stats to the terminal
 type Display struct {
-	statsChan     chan *models.LogStats
-	alertChan     chan models.Alert
-	stopChan      chan struct{}
 	alerts        []models.Alert
-	maxAlerts     int
+	maxAlerts     int           // Most recent alerts shown
+	alertHistory  int           // Alerts kept, see SetAlertRetention
+	alertMaxAge   time.Duration // Alerts older than this are dropped, 0 keeps them
+	topN          int           // Top errors, patterns and sources listed
+	levels        []string      // Display order of log levels
+	output        OutputMode
+	location      *time.Location  // Zone times are shown in, nil for the defaults (see SetLocation)
+	noClear       bool            // Print changed lines instead of redrawing, see append.go
+	once          bool            // Only render the final stats, see SetOnce
+	color         bool            // Colorize levels and alerts, see color.go
+	lastLines     map[string]bool // Lines of the previous report in no-clear mode
+	outMux        sync.Mutex      // Serializes writes when alerts are printed as they arrive
 	clearScreenFn func()
+
+	// Rate-limiting of renders, see refresh.go. renderMux also guards
+	// alerts, since held stats are rendered from a timer goroutine.
+	refresh      time.Duration
+	renderMux    sync.Mutex
+	lastRender   time.Time
+	pending      *models.LogStats // Latest stats not yet rendered
+	refreshTimer *time.Timer      // Renders pending, set while stats are held
+}
+
+// DefaultTopN is how many top errors, emerging patterns and sources are
+// listed by default
+const DefaultTopN = 3
+
+// Default alert retention, see SetAlertRetention
+const (
+	DefaultAlertHistory = 50
+	DefaultAlertShow    = 12
+)
+
+// OutputMode selects how the display writes stats and alerts
+type OutputMode string
+
+const (
+	// OutputText redraws a full report on the terminal
+	OutputText OutputMode = "text"
+	// OutputJSON prints one JSON object per stats update or alert
+	OutputJSON OutputMode = "json"
+	// OutputOneLine prints one key=value status line per stats update
+	OutputOneLine OutputMode = "oneline"
+)
+
+// ParseOutputMode validates an output mode name
+func ParseOutputMode(name string) (OutputMode, error) {
+	switch mode := OutputMode(name); mode {
+	case OutputText, OutputJSON, OutputOneLine:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown output mode %q", name)
+	}
 }
 
-// NewDisplay creates a new Display
-func NewDisplay(statsChan chan *models.LogStats, alertChan chan models.Alert) *Display {
+// NewDisplay creates a new Display. It is a notify.Sink; stats and alerts
+// reach it through HandleStats and HandleAlert.
+func NewDisplay() *Display {
 	return &Display{
-		statsChan:     statsChan,
-		alertChan:     alertChan,
-		stopChan:      make(chan struct{}),
 		alerts:        make([]models.Alert, 0, 10),
-		maxAlerts:     12, // Show the 12 most recent alerts
+		maxAlerts:     DefaultAlertShow,
+		alertHistory:  DefaultAlertHistory,
+		topN:          DefaultTopN,
+		levels:        []string{"FATAL", "ERROR", "WARN", "INFO", "DEBUG"},
+		output:        OutputText,
 		clearScreenFn: clearScreen,
 	}
 }
 
-// Start begins updating the display
-func (d *Display) Start() {
-	go d.collectAlerts()
-	go d.updateDisplay()
+// SetLevels sets the order in which log levels are listed
+func (d *Display) SetLevels(levels []string) {
+	if len(levels) > 0 {
+		d.levels = levels
+	}
 }
 
-// Stop signals the display to stop
-func (d *Display) Stop() {
-	close(d.stopChan)
+// SetTopN sets how many top errors, emerging patterns and sources are
+// listed. The analyzer reports at most 10 of each.
+func (d *Display) SetTopN(n int) error {
+	if n < 1 {
+		return fmt.Errorf("must be at least 1, got %d", n)
+	}
+
+	d.topN = n
+	return nil
 }
 
-func (d *Display) collectAlerts() {
-	for {
-		select {
-		case <-d.stopChan:
-			return
-		case alert := <-d.alertChan:
-			d.alerts = append(d.alerts, alert)
-			if len(d.alerts) > 50 { // Keep a reasonable history
-				d.alerts = d.alerts[1:]
-			}
-		}
+// SetAlertRetention sets how many alerts are kept (history) and how many
+// of the most recent are shown (show). Alerts older than maxAge are
+// dropped whatever their number, so a quiet period clears stale alerts;
+// 0 keeps alerts until newer ones push them out.
+func (d *Display) SetAlertRetention(history, show int, maxAge time.Duration) error {
+	if history < 1 {
+		return fmt.Errorf("history must be at least 1, got %d", history)
+	}
+	if show < 1 || show > history {
+		return fmt.Errorf("shown alerts must be between 1 and the history of %d, got %d", history, show)
 	}
+	if maxAge < 0 {
+		return fmt.Errorf("max age must not be negative, got %s", maxAge)
+	}
+
+	d.alertHistory = history
+	d.maxAlerts = show
+	d.alertMaxAge = maxAge
+	return nil
 }
 
-func (d *Display) updateDisplay() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// pruneAlerts drops alerts older than the max age at now. The caller must
+// hold renderMux.
+func (d *Display) pruneAlerts(now time.Time) {
+	if d.alertMaxAge == 0 {
+		return
+	}
 
-	for {
-		select {
-		case <-d.stopChan:
-			return
-		case stats := <-d.statsChan:
-			d.render(stats)
-		case <-ticker.C:
-			// Just trigger refresh if needed
-		}
+	cutoff := now.Add(-d.alertMaxAge)
+	expired := 0
+	for expired < len(d.alerts) && d.alerts[expired].Timestamp.Before(cutoff) {
+		expired++
+	}
+	d.alerts = d.alerts[expired:]
+}
+
+// SetOnce switches to batch mode: stats updates are not rendered and the
+// screen is never cleared, so RenderFinal prints a single report. Alerts
+// are still collected for it, and printed as they fire in JSON mode.
+func (d *Display) SetOnce(once bool) {
+	d.once = once
+	if once {
+		d.clearScreenFn = func() {}
+	}
+}
+
+// SetLocation sets the zone the report header, alert and no-clear times are
+// shown in. By default the header is in UTC and the others in local time.
+func (d *Display) SetLocation(loc *time.Location) {
+	d.location = loc
+}
+
+// inLocation returns t in the zone set by SetLocation, or def if none is set
+func (d *Display) inLocation(t time.Time, def *time.Location) time.Time {
+	if d.location != nil {
+		return t.In(d.location)
+	}
+	return t.In(def)
+}
+
+// SetOutput selects the output mode
+func (d *Display) SetOutput(mode OutputMode) {
+	d.output = mode
+}
+
+// RenderFinal renders stats once more after the dispatcher has been
+// stopped, so the final snapshot is always shown before exit. Any held
+// stats are superseded by it.
+func (d *Display) RenderFinal(stats *models.LogStats) {
+	d.renderMux.Lock()
+	defer d.renderMux.Unlock()
+
+	if d.refreshTimer != nil {
+		d.refreshTimer.Stop()
+		d.refreshTimer = nil
+	}
+	d.pending = nil
+	d.renderLocked(stats)
+}
+
+// renderStats renders stats in the selected output mode
+func (d *Display) renderStats(stats *models.LogStats) {
+	switch d.output {
+	case OutputJSON:
+		d.renderJSON(stats)
+	case OutputOneLine:
+		d.renderOneLine(stats)
+	default:
+		d.render(stats)
+	}
+}
+
+// HandleAlert implements notify.Sink. Alerts are listed with the next
+// report, and printed right away in JSON mode.
+func (d *Display) HandleAlert(alert models.Alert) {
+	d.renderMux.Lock()
+	defer d.renderMux.Unlock()
+
+	if d.output == OutputJSON {
+		d.writeJSON(newJSONAlert(alert))
+	}
+	d.alerts = append(d.alerts, alert)
+	if len(d.alerts) > d.alertHistory {
+		d.alerts = d.alerts[len(d.alerts)-d.alertHistory:]
 	}
 }
 
@@ -81,12 +217,12 @@ func (d *Display) render(stats *models.LogStats) {
 	d.clearScreenFn()
 
 	// Format timestamp
-	timestamp := stats.LastUpdated.UTC().Format("2006-01-02 15:04:05 UTC")
+	timestamp := d.inLocation(stats.LastUpdated, time.UTC).Format("2006-01-02 15:04:05 MST")
 
 	// Format window size with previous window size if it changed
 	windowSizeText := fmt.Sprintf("%d sec", stats.WindowSize)
 	if stats.PreviousWindowSize > 0 && stats.PreviousWindowSize != stats.WindowSize {
-		windowSizeText = fmt.Sprintf("%d sec (Adjusted from %d sec)", 
+		windowSizeText = fmt.Sprintf("%d sec (Adjusted from %d sec)",
 			stats.WindowSize, stats.PreviousWindowSize)
 	}
 
@@ -96,17 +232,83 @@ Log Analysis Report (Last Updated: %s)
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 Runtime Stats:
 • Entries Processed: %s
-• Current Rate: %.0f entries/sec (Peak: %.0f entries/sec)
-• Adaptive Window: %s
-
-Pattern Analysis:`,
+• Current Rate: %.0f entries/sec (Smoothed: %.0f, Peak: %.0f entries/sec)
+• Adaptive Window: %s`,
 		timestamp,
 		formatNumber(stats.EntriesProcessed),
 		stats.CurrentRate,
+		stats.SmoothedRate,
 		stats.PeakRate,
 		windowSizeText,
 	)
 
+	// Add the learned baseline rate anomalies are measured against
+	if stats.BaselineRate > 0 {
+		report += fmt.Sprintf("\n• Baseline Rate: %.0f ± %.0f entries/sec", stats.BaselineRate, stats.BaselineStdDev)
+	}
+
+	// Add the share of lines that parsed, a quick check of the format settings
+	if stats.TotalLines > 0 {
+		report += fmt.Sprintf("\n• Parse Success Rate: %.1f%% (%s lines)", stats.ParseSuccessRate(), formatNumber(stats.TotalLines))
+	}
+
+	// Add the error budget left against the SLO
+	if slo := stats.SLO; slo.Target > 0 {
+		line := fmt.Sprintf("• SLO %g%%: %.2f%% success, %.1f%% of error budget left, burn %.1fx (5m) / %.1fx (1h)",
+			slo.Target, slo.SuccessRate, slo.BudgetRemaining, slo.FastBurnRate, slo.SlowBurnRate)
+		if slo.BudgetRemaining <= 0 {
+			line = d.colorize(line, ansiRed)
+		}
+		report += "\n" + line
+	}
+
+	// Add skipped entries broken down by why they failed to parse
+	if stats.SkippedEntries > 0 {
+		report += fmt.Sprintf("\n• Skipped Entries: %s (%s)", formatNumber(stats.SkippedEntries), formatRejects(stats.RejectCounts))
+	}
+
+	// Add the entry buffer once a burst has grown it
+	if stats.BufferResized {
+		report += fmt.Sprintf("\n• Entry Buffer: %s of %s entries waiting (resized for bursts)",
+			formatNumber(stats.BufferUsed), formatNumber(stats.BufferSize))
+	}
+
+	// Add entries lost to backpressure, so an overloaded pipeline is visible
+	if stats.DroppedEntries > 0 {
+		report += fmt.Sprintf("\n⚠️ Dropped Entries: %s (analyzer could not keep up)", formatNumber(stats.DroppedEntries))
+	}
+
+	// Add entries dropped on purpose by the level or line filters
+	if stats.FilteredEntries > 0 {
+		report += fmt.Sprintf("\n• Filtered Entries: %s (dropped by level or line filters)", formatNumber(stats.FilteredEntries))
+	}
+
+	// Add the recent rate trend
+	if len(stats.RateHistory) > 1 {
+		report += fmt.Sprintf("\n• Rate Trend (%ds): %s", len(stats.RateHistory), sparkline(stats.RateHistory))
+	}
+
+	// Add the coarser trend once there is more history than the line above
+	if counts := rateTier(stats, 10); len(counts) > 6 {
+		report += fmt.Sprintf("\n• Rate Trend (%s, 10s points): %s",
+			time.Duration(len(counts))*10*time.Second, sparkline(counts))
+	}
+
+	// Add the long-term rate trend, which the window does not limit
+	if series := stats.Lifetime.RateSeries; len(series) > 1 {
+		elapsed := stats.LastUpdated.Sub(stats.Lifetime.Since).Round(time.Second)
+		report += fmt.Sprintf("\n• Since Start (%s): %s avg %.0f entries/sec, %ds per point",
+			elapsed, sparkline(series), float64(stats.EntriesProcessed)/elapsed.Seconds(), stats.Lifetime.RateResolution)
+	}
+
+	// Add latency percentiles when the logs carry durations
+	if stats.LatencySamples > 0 {
+		report += fmt.Sprintf("\n• Latency: p50 %.0fms, p95 %.0fms, p99 %.0fms (%s samples)",
+			stats.LatencyP50, stats.LatencyP95, stats.LatencyP99, formatNumber(stats.LatencySamples))
+	}
+
+	report += "\n\nPattern Analysis:"
+
 	// Add log level distribution
 	totalLogs := 0
 	for _, count := range stats.LevelCounts {
@@ -114,15 +316,17 @@ Pattern Analysis:`,
 	}
 
 	if totalLogs > 0 {
-		// Sort levels for consistent display
-		levels := []string{"ERROR", "INFO", "DEBUG"}
-		for _, level := range levels {
-			count, ok := stats.LevelCounts[level]
-			if ok {
-				percentage := 100.0 * float64(count) / float64(totalLogs)
-				report += fmt.Sprintf("\n• %s: %.0f%% (%s entries)", 
-					level, percentage, formatNumber(count))
+		// Show levels in the configured order for consistent display
+		for _, level := range d.orderedLevels(stats.LevelCounts) {
+			count := stats.LevelCounts[level]
+			percentage := 100.0 * float64(count) / float64(totalLogs)
+			line := fmt.Sprintf("%s %s: %.0f%% (%s entries, %.1f/sec, %s since start)",
+				levelBullet(level), level, percentage, formatNumber(count), stats.LevelRates[level],
+				formatNumber(stats.Lifetime.LevelCounts[level]))
+			if color := levelColor(level); color != "" {
+				line = d.colorize(line, color)
 			}
+			report += "\n" + line
 		}
 	}
 
@@ -130,111 +334,254 @@ Pattern Analysis:`,
 	report += "\n\nDynamic Insights:"
 
 	// Calculate total error rate
-	totalErrorRate := 0.0
-	for _, rate := range stats.ErrorRates {
-		totalErrorRate += rate
-	}
+	totalErrorRate := totalErrorRate(stats)
 
 	// Add total error rate if we have errors
 	if totalErrorRate > 0 {
 		report += fmt.Sprintf("\n• Error Rate: %.1f errors/sec", totalErrorRate)
 	}
 
-	// Add emerging patterns if any
-	if len(stats.EmergingPatterns) > 0 {
-		// Sort patterns by change percentage
-		var patterns []struct {
-			Name    string
-			Change  float64
-		}
-		
-		for pattern, change := range stats.EmergingPatterns {
-			patterns = append(patterns, struct {
-				Name    string
-				Change  float64
-			}{pattern, change})
-		}
-		
-		sort.Slice(patterns, func(i, j int) bool {
-			return patterns[i].Change > patterns[j].Change
-		})
-		
-		// Take the top pattern
-		if len(patterns) > 0 {
-			report += fmt.Sprintf("\n• Emerging Pattern: \"%s\" spiked %.0f%% in last 15 sec",
-				patterns[0].Name, patterns[0].Change)
-		}
+	// Add warning rate, a lower-priority signal than errors
+	if stats.WarningRate > 0 {
+		report += fmt.Sprintf("\n⚠️ Warning Rate: %.1f warnings/sec", stats.WarningRate)
+	}
+
+	// Add the top emerging patterns if any
+	patterns := sortedPatterns(stats)
+	for _, pattern := range patterns[:min(d.topN, len(patterns))] {
+		report += fmt.Sprintf("\n• Emerging Pattern: \"%s\" spiked %.0f%% recently",
+			pattern.Pattern, pattern.Change)
 	}
 
 	// Add emerging pattern history section
 	if len(stats.EmergingPatternHistory) > 0 {
 		report += "\n\nEmerging Pattern History:"
-		
+
 		// Loop through history in reverse to show most recent first
 		for i := len(stats.EmergingPatternHistory) - 1; i >= 0; i-- {
 			event := stats.EmergingPatternHistory[i]
-			
-			// Skip if the event has expired (more than 60 seconds old)
-			if time.Since(event.StartTime) > 60*time.Second {
+
+			// Skip if the event has expired (more than 60 seconds old),
+			// measured on the analyzer's clock like the event itself
+			age := stats.LastUpdated.Sub(event.StartTime)
+			if age > 60*time.Second {
 				continue
 			}
-			
+
 			// Format time since the event
-			timeSince := time.Since(event.StartTime).Seconds()
-			report += fmt.Sprintf("\n• [%.0f sec ago] \"%s\" spiked %.0f%%", 
+			timeSince := age.Seconds()
+			report += fmt.Sprintf("\n• [%.0f sec ago] \"%s\" spiked %.0f%%",
 				timeSince, event.Pattern, event.PeakChange)
 		}
 	}
 
+	// Add inter-arrival histogram, showing whether load is steady or bursty
+	if gaps := histogramTotal(stats.InterArrival); gaps > 0 {
+		report += "\n\n• Arrival Gaps:"
+		for _, bucket := range stats.InterArrival {
+			share := float64(bucket.Count) / float64(gaps)
+			report += fmt.Sprintf("\n  %-8s %-20s %3.0f%%",
+				bucket.Label, strings.Repeat("█", int(share*20+0.5)), 100*share)
+		}
+	}
+
 	// Add top errors
-	if len(stats.ErrorCounts) > 0 {
-		// Sort errors by count
-		type errorEntry struct {
-			Type  string
-			Count int
+	if len(stats.TopErrors) > 0 {
+		report += "\n\n• Top Errors:"
+		count := min(d.topN, len(stats.TopErrors))
+		for i := 0; i < count; i++ {
+			topError := stats.TopErrors[i]
+			report += fmt.Sprintf("\n  %d. %s (%s occurrences, %s since start)",
+				i+1, topError.Type, formatNumber(topError.Count),
+				formatNumber(stats.Lifetime.ErrorCounts[topError.Type]))
+
+			// Show the multiplier of error types boosted by a recent spike
+			if topError.Weight > 1 {
+				report += fmt.Sprintf(" ×%g weight", topError.Weight)
+			}
 		}
-		
-		var errors []errorEntry
-		for errType, count := range stats.ErrorCounts {
-			errors = append(errors, errorEntry{errType, count})
+	}
+
+	// Add top source IPs
+	if len(stats.TopIPs) > 0 {
+		report += fmt.Sprintf("\n\n• Top Sources (%s unique in window, ~%s since start):",
+			formatNumber(stats.UniqueIPs), formatNumber(stats.Lifetime.UniqueIPs))
+		count := min(d.topN, len(stats.TopIPs))
+		for i := 0; i < count; i++ {
+			report += fmt.Sprintf("\n  %d. %s (%s requests)",
+				i+1, stats.TopIPs[i].IP, formatNumber(stats.TopIPs[i].Count))
 		}
-		
-		sort.Slice(errors, func(i, j int) bool {
-			return errors[i].Count > errors[j].Count
-		})
-		
-		report += "\n\n• Top Errors:"
-		count := min(3, len(errors))
+	}
+
+	// Add the most frequent values of the group-by field
+	if len(stats.TopGroups) > 0 {
+		report += fmt.Sprintf("\n\n• Top %s:", stats.GroupBy)
+		count := min(d.topN, len(stats.TopGroups))
 		for i := 0; i < count; i++ {
-			report += fmt.Sprintf("\n  %d. %s (%s occurrences)",
-				i+1, errors[i].Type, formatNumber(errors[i].Count))
+			report += fmt.Sprintf("\n  %d. %s (%s entries)",
+				i+1, stats.TopGroups[i].Value, formatNumber(stats.TopGroups[i].Count))
 		}
 	}
 
-	// Add alerts
+	// Add alerts, leaving out those past the max age
+	d.pruneAlerts(stats.LastUpdated)
 	if len(d.alerts) > 0 {
 		report += "\n\nSelf-Evolving Alerts:"
-		
+
 		// Get the most recent alerts (up to maxAlerts)
 		start := len(d.alerts) - d.maxAlerts
 		if start < 0 {
 			start = 0
 		}
-		
+
 		for i := start; i < len(d.alerts); i++ {
 			alert := d.alerts[i]
-			timestamp := alert.Timestamp.Format("15:04:05")
-			report += fmt.Sprintf("\n[%s] %s", timestamp, alert.Message)
+			timestamp := d.inLocation(alert.Timestamp, time.Local).Format("15:04:05")
+			report += "\n" + d.colorize(fmt.Sprintf("[%s] %s", timestamp, alert.Message), alertColor(alert.Severity))
 		}
 	}
 
 	// Add footer
-	report += "\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\nPress Ctrl+C to exit\n"
+	report += "\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
+	if !d.once {
+		report += "Press Ctrl+C to exit\n"
+	}
 
 	// Print the report
+	if d.noClear {
+		d.printChanges(stats.LastUpdated, report)
+		return
+	}
 	fmt.Print(report)
 }
 
+// orderedLevels returns the levels present in counts, configured levels
+// first in their configured order, followed by any others alphabetically
+func (d *Display) orderedLevels(counts map[string]int) []string {
+	ordered := make([]string, 0, len(counts))
+	known := make(map[string]bool, len(d.levels))
+	for _, level := range d.levels {
+		known[level] = true
+		if _, ok := counts[level]; ok {
+			ordered = append(ordered, level)
+		}
+	}
+
+	var others []string
+	for level := range counts {
+		if !known[level] {
+			others = append(others, level)
+		}
+	}
+	sort.Strings(others)
+
+	return append(ordered, others...)
+}
+
+// patternChange is an emerging pattern with its percentage increase
+type patternChange struct {
+	Pattern string  `json:"pattern"`
+	Change  float64 `json:"change_percent"`
+}
+
+// sortedPatterns returns the emerging patterns sorted by change percentage
+func sortedPatterns(stats *models.LogStats) []patternChange {
+	patterns := make([]patternChange, 0, len(stats.EmergingPatterns))
+	for pattern, change := range stats.EmergingPatterns {
+		patterns = append(patterns, patternChange{pattern, change})
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].Change > patterns[j].Change
+	})
+	return patterns
+}
+
+// totalErrorRate sums the per-type error rates
+func totalErrorRate(stats *models.LogStats) float64 {
+	total := 0.0
+	for _, rate := range stats.ErrorRates {
+		total += rate
+	}
+	return total
+}
+
+// formatRejects lists reject reasons with their counts, most common first
+func formatRejects(counts map[string]int) string {
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s: %s", reason, formatNumber(counts[reason]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rateTier returns the entry counts at the given resolution in seconds, or
+// nil when the stats have no such tier
+func rateTier(stats *models.LogStats, resolutionSec int) []int {
+	for _, tier := range stats.RateTiers {
+		if tier.ResolutionSec == resolutionSec {
+			return tier.Counts
+		}
+	}
+	return nil
+}
+
+// histogramTotal sums the counts of all buckets
+func histogramTotal(buckets []models.HistogramBucket) int {
+	total := 0
+	for _, bucket := range buckets {
+		total += bucket.Count
+	}
+	return total
+}
+
+// sparkBlocks are the sparkline characters from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a row of block characters scaled to the maximum
+func sparkline(values []int) string {
+	maxValue := 0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if maxValue > 0 {
+			level = v * (len(sparkBlocks) - 1) / maxValue
+		}
+		line[i] = sparkBlocks[level]
+	}
+	return string(line)
+}
+
+// levelBullet picks the list marker for a level, so critical and warning
+// levels stand out from the rest
+func levelBullet(level string) string {
+	switch models.LevelSeverity(level) {
+	case models.SeverityCritical:
+		return "🚨"
+	case models.SeverityWarning:
+		return "⚠️"
+	default:
+		return "•"
+	}
+}
+
 // Helper functions
 func formatNumber(n int) string {
 	if n < 1000 {
@@ -252,4 +599,4 @@ func min(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
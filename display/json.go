@@ -0,0 +1,128 @@
+// display/json.go - Machine-readable output mode printing JSON lines.
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// jsonStats mirrors the fields of the text report
+type jsonStats struct {
+	Type             string                   `json:"type"`
+	Timestamp        time.Time                `json:"timestamp"`
+	EntriesProcessed int                      `json:"entries_processed"`
+	TotalLines       int                      `json:"total_lines"`
+	ParseSuccessRate float64                  `json:"parse_success_rate"`
+	CurrentRate      float64                  `json:"current_rate"`
+	SmoothedRate     float64                  `json:"smoothed_rate"`
+	PeakRate         float64                  `json:"peak_rate"`
+	WindowSize       int                      `json:"window_size"`
+	LevelPercentages map[string]float64       `json:"level_percentages"`
+	LevelRates       map[string]float64       `json:"level_rates"`
+	ErrorRate        float64                  `json:"error_rate"`
+	WarningRate      float64                  `json:"warning_rate"`
+	EmergingPatterns []patternChange          `json:"emerging_patterns"`
+	TopErrors        []models.WeightedError   `json:"top_errors"`
+	TopSources       []models.IPCount         `json:"top_sources"`
+	UniqueIPs        int                      `json:"unique_ips"`
+	GroupBy          string                   `json:"group_by,omitempty"`
+	TopGroups        []models.FieldCount      `json:"top_groups,omitempty"`
+	InterArrival     []models.HistogramBucket `json:"inter_arrival"`
+	LatencySamples   int                      `json:"latency_samples,omitempty"`
+	LatencyP50       float64                  `json:"latency_p50_ms,omitempty"`
+	LatencyP95       float64                  `json:"latency_p95_ms,omitempty"`
+	LatencyP99       float64                  `json:"latency_p99_ms,omitempty"`
+	Lifetime         models.LifetimeStats     `json:"lifetime"`
+	SLO              *models.SLOStats         `json:"slo,omitempty"`
+}
+
+// jsonAlert is an alert line, told apart from stats by its type
+type jsonAlert struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Message   string          `json:"message"`
+	Severity  models.Severity `json:"severity"`
+}
+
+func newJSONAlert(alert models.Alert) jsonAlert {
+	return jsonAlert{
+		Type:      "alert",
+		Timestamp: alert.Timestamp,
+		Message:   alert.Message,
+		Severity:  alert.Severity,
+	}
+}
+
+// renderJSON prints stats as a single JSON line, without clearing the screen
+func (d *Display) renderJSON(stats *models.LogStats) {
+	if stats == nil {
+		return
+	}
+
+	totalLogs := 0
+	for _, count := range stats.LevelCounts {
+		totalLogs += count
+	}
+	percentages := make(map[string]float64, len(stats.LevelCounts))
+	for level, count := range stats.LevelCounts {
+		percentages[level] = 100.0 * float64(count) / float64(totalLogs)
+	}
+
+	patterns := sortedPatterns(stats)
+	patterns = patterns[:min(d.topN, len(patterns))]
+	errors := stats.TopErrors[:min(d.topN, len(stats.TopErrors))]
+	sources := stats.TopIPs[:min(d.topN, len(stats.TopIPs))]
+	groups := stats.TopGroups[:min(d.topN, len(stats.TopGroups))]
+
+	var slo *models.SLOStats
+	if stats.SLO.Target > 0 {
+		slo = &stats.SLO
+	}
+
+	d.writeJSON(jsonStats{
+		Type:             "stats",
+		Timestamp:        stats.LastUpdated,
+		EntriesProcessed: stats.EntriesProcessed,
+		TotalLines:       stats.TotalLines,
+		ParseSuccessRate: stats.ParseSuccessRate(),
+		CurrentRate:      stats.CurrentRate,
+		SmoothedRate:     stats.SmoothedRate,
+		PeakRate:         stats.PeakRate,
+		WindowSize:       stats.WindowSize,
+		LevelPercentages: percentages,
+		LevelRates:       stats.LevelRates,
+		ErrorRate:        totalErrorRate(stats),
+		WarningRate:      stats.WarningRate,
+		EmergingPatterns: patterns,
+		TopErrors:        errors,
+		TopSources:       sources,
+		UniqueIPs:        stats.UniqueIPs,
+		GroupBy:          stats.GroupBy,
+		TopGroups:        groups,
+		InterArrival:     stats.InterArrival,
+		LatencySamples:   stats.LatencySamples,
+		LatencyP50:       stats.LatencyP50,
+		LatencyP95:       stats.LatencyP95,
+		LatencyP99:       stats.LatencyP99,
+		Lifetime:         stats.Lifetime,
+		SLO:              slo,
+	})
+}
+
+// writeJSON prints v as one line on stdout
+func (d *Display) writeJSON(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode output: %v\n", err)
+		return
+	}
+
+	d.outMux.Lock()
+	defer d.outMux.Unlock()
+	fmt.Println(string(data))
+}
@@ -0,0 +1,29 @@
+// display/oneline.go - Terse one-line status per update, for feeding other dashboards.
+
+package display
+
+import (
+	"fmt"
+
+	"log_analyzer/models"
+)
+
+// renderOneLine prints the headline numbers of stats as a single line of
+// space-separated key=value pairs, e.g.
+//
+//	rate=1234/s peak=5000/s err=2.3/s window=60s patterns=2
+//
+// The keys and their order are stable, so the line can be parsed by other
+// tools. Alerts are not printed in this mode.
+func (d *Display) renderOneLine(stats *models.LogStats) {
+	if stats == nil {
+		return
+	}
+
+	line := fmt.Sprintf("rate=%.0f/s peak=%.0f/s err=%.1f/s window=%ds patterns=%d\n",
+		stats.CurrentRate, stats.PeakRate, totalErrorRate(stats), stats.WindowSize, len(stats.EmergingPatterns))
+
+	d.outMux.Lock()
+	defer d.outMux.Unlock()
+	fmt.Print(line)
+}
@@ -0,0 +1,66 @@
+// display/refresh.go - Rate-limiting of renders, coalescing stats that arrive too fast.
+
+package display
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// SetRefresh sets the minimum interval between two renders. Stats arriving
+// sooner are coalesced and the latest is rendered once the interval has
+// passed, so a fast stats producer does not make the terminal flicker.
+// 0 renders every update.
+func (d *Display) SetRefresh(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("must not be negative, got %s", interval)
+	}
+
+	d.refresh = interval
+	return nil
+}
+
+// HandleStats implements notify.Sink by rendering the stats, or holding
+// them until the refresh interval has passed
+func (d *Display) HandleStats(stats *models.LogStats) {
+	if d.once {
+		return
+	}
+
+	d.renderMux.Lock()
+	defer d.renderMux.Unlock()
+
+	wait := d.refresh - time.Since(d.lastRender)
+	if wait <= 0 {
+		d.pending = nil
+		d.renderLocked(stats)
+		return
+	}
+
+	// Only the latest stats are worth rendering, so replace any held ones
+	d.pending = stats
+	if d.refreshTimer == nil {
+		d.refreshTimer = time.AfterFunc(wait, d.renderPending)
+	}
+}
+
+// renderPending renders the held stats once the refresh interval has passed
+func (d *Display) renderPending() {
+	d.renderMux.Lock()
+	defer d.renderMux.Unlock()
+
+	d.refreshTimer = nil
+	if d.pending != nil {
+		stats := d.pending
+		d.pending = nil
+		d.renderLocked(stats)
+	}
+}
+
+// renderLocked renders stats and notes when. The caller must hold renderMux.
+func (d *Display) renderLocked(stats *models.LogStats) {
+	d.lastRender = time.Now()
+	d.renderStats(stats)
+}
@@ -0,0 +1,196 @@
+// generator/generator.go - Synthetic log streams in the analyzer's default
+// format, for stress tests and reproducible integration tests.
+
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// tickInterval is how often a live stream writes the entries that came due
+const tickInterval = 100 * time.Millisecond
+
+// ErrorMessages are the messages of generated ERROR entries, as written by
+// log_generator.sh
+var ErrorMessages = []string{
+	"Database connection failed",
+	"Null pointer exception",
+	"File not found",
+	"Access denied",
+	"Out of memory",
+	"Network timeout occurred",
+	"Illegal argument provided",
+	"User authentication failed",
+}
+
+// Options configures a Generator. The zero value of Count, BurstSize and
+// Start disables the limit, bursts and synthetic time respectively.
+type Options struct {
+	Rate       float64       // Entries per second between bursts
+	ErrorRatio float64       // Share (0-1) of entries logged as ERROR; the rest are split between INFO and DEBUG
+	BurstSize  int           // Extra entries written at once every BurstEvery
+	BurstEvery time.Duration // Interval between bursts
+	Count      int           // Stop after this many entries
+	Seed       int64         // Seeds levels, messages and IPs, so a seed always gives the same sequence
+
+	// Start switches to synthetic time: timestamps begin at Start and
+	// advance by 1/Rate per entry, and entries are written as fast as
+	// possible. With a Count, the output is then fully reproducible.
+	Start time.Time
+}
+
+// DefaultOptions returns the options used by log_generator when no flags
+// are given
+func DefaultOptions() Options {
+	return Options{
+		Rate:       100,
+		ErrorRatio: 1.0 / 3, // Same mix as log_generator.sh
+		BurstEvery: 10 * time.Second,
+		Seed:       1,
+	}
+}
+
+// Generator writes synthetic log lines
+type Generator struct {
+	opts    Options
+	rng     *rand.Rand
+	written int
+}
+
+// New validates opts and creates a Generator
+func New(opts Options) (*Generator, error) {
+	if opts.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be above 0, got %g", opts.Rate)
+	}
+	if opts.ErrorRatio < 0 || opts.ErrorRatio > 1 {
+		return nil, fmt.Errorf("error ratio must be between 0 and 1, got %g", opts.ErrorRatio)
+	}
+	if opts.BurstSize < 0 {
+		return nil, fmt.Errorf("burst size must not be negative, got %d", opts.BurstSize)
+	}
+	if opts.BurstSize > 0 && opts.BurstEvery <= 0 {
+		return nil, fmt.Errorf("burst interval must be above 0, got %s", opts.BurstEvery)
+	}
+	if opts.Count < 0 {
+		return nil, fmt.Errorf("count must not be negative, got %d", opts.Count)
+	}
+
+	return &Generator{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+	}, nil
+}
+
+// Run writes entries to w until Count entries have been written or ctx is
+// cancelled, and returns how many were written
+func (g *Generator) Run(ctx context.Context, w io.Writer) (int, error) {
+	out := bufio.NewWriter(w)
+
+	var err error
+	if g.opts.Start.IsZero() {
+		err = g.runLive(ctx, out)
+	} else {
+		err = g.runSynthetic(ctx, out)
+	}
+	if flushErr := out.Flush(); err == nil {
+		err = flushErr
+	}
+	return g.written, err
+}
+
+// runLive paces entries at Rate in real time, stamped with the wall clock
+func (g *Generator) runLive(ctx context.Context, out *bufio.Writer) error {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	nextBurst := last.Add(g.opts.BurstEvery)
+	due := 0.0 // Entries owed since the last tick, including fractions
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			due += g.opts.Rate * now.Sub(last).Seconds()
+			last = now
+
+			n := int(due)
+			due -= float64(n)
+			if g.opts.BurstSize > 0 && !now.Before(nextBurst) {
+				n += g.opts.BurstSize
+				nextBurst = nextBurst.Add(g.opts.BurstEvery)
+			}
+
+			for i := 0; i < n; i++ {
+				done, err := g.writeEntry(out, now)
+				if err != nil || done {
+					return err
+				}
+			}
+			// Hand each tick's entries on right away, so the stream looks live
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// runSynthetic writes entries as fast as possible with timestamps advancing
+// from Start, so the output does not depend on when or how fast it runs
+func (g *Generator) runSynthetic(ctx context.Context, out *bufio.Writer) error {
+	step := time.Duration(float64(time.Second) / g.opts.Rate)
+	nextBurst := g.opts.Start.Add(g.opts.BurstEvery)
+
+	for timestamp := g.opts.Start; ; timestamp = timestamp.Add(step) {
+		if err := ctx.Err(); err != nil {
+			return nil
+		}
+
+		n := 1
+		if g.opts.BurstSize > 0 && !timestamp.Before(nextBurst) {
+			n += g.opts.BurstSize
+			nextBurst = nextBurst.Add(g.opts.BurstEvery)
+		}
+
+		for i := 0; i < n; i++ {
+			done, err := g.writeEntry(out, timestamp)
+			if err != nil || done {
+				return err
+			}
+		}
+	}
+}
+
+// writeEntry writes one random entry stamped with timestamp. It reports
+// whether Count has been reached.
+func (g *Generator) writeEntry(out *bufio.Writer, timestamp time.Time) (bool, error) {
+	if g.opts.Count > 0 && g.written >= g.opts.Count {
+		return true, nil
+	}
+
+	ip := fmt.Sprintf("192.168.%d.%d", g.rng.Intn(254)+1, g.rng.Intn(254)+1)
+	stamp := timestamp.UTC().Format("2006-01-02T15:04:05Z")
+
+	var err error
+	switch {
+	case g.rng.Float64() < g.opts.ErrorRatio:
+		message := ErrorMessages[g.rng.Intn(len(ErrorMessages))]
+		_, err = fmt.Fprintf(out, "[%s] ERROR - IP:%s Error 500 - %s\n", stamp, ip, message)
+	case g.rng.Intn(2) == 0:
+		_, err = fmt.Fprintf(out, "[%s] INFO - IP:%s\n", stamp, ip)
+	default:
+		_, err = fmt.Fprintf(out, "[%s] DEBUG - IP:%s\n", stamp, ip)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	g.written++
+	return g.opts.Count > 0 && g.written >= g.opts.Count, nil
+}
@@ -1,59 +1,613 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"log_analyzer/analyzer"
 	"log_analyzer/display"
 	"log_analyzer/models"
+	"log_analyzer/pipeline"
+	"log_analyzer/queue"
 	"log_analyzer/reader"
 )
 
-const (
-	LogChannelSize   = 50000
-	StatsChannelSize = 10
-	AlertChannelSize = 100
-)
-
 func main() {
 
 	// Start with smaller buffer size in order to test buffer resize events more thoroughly
 	bufferSize := flag.Int("buffer", 10000, "Initial buffer size for log entries")
+	bufferGrowAt := flag.Float64("buffer-grow-at", analyzer.DefaultBufferGrowAt, "Fill ratio (0-1] of the entry buffer at which it grows")
+	bufferGrowFactor := flag.Float64("buffer-grow-factor", analyzer.DefaultBufferGrowFactor, "Factor the entry buffer grows by, up to 10 times -buffer")
+	bufferShrinkBelow := flag.Float64("buffer-shrink-below", analyzer.DefaultBufferShrinkBelow, "Shrink a grown entry buffer towards -buffer once the rate stays below this fraction of its capacity (0 disables)")
 
 	// Parse command-line flags
 	debugMode := flag.Bool("debug", false, "Enable debug mode with detailed logging")
+	follow := flag.Bool("follow", false, "Keep reading a log file as it grows, like tail -f")
+	listenAddr := flag.String("listen", "", "Receive log lines from the network instead of files or stdin: udp://host:port (one or more lines per datagram) or tcp://host:port (newline-delimited, any number of clients)")
+	once := flag.Bool("once", false, "Read all input, including stdin, to the end and print a single report instead of the live display")
+	jsonMode := flag.Bool("json", false, "Parse each log line as a JSON object")
+	jsonFieldSpec := flag.String("json-fields", "", "JSON key mapping, e.g. timestamp=ts,level=level,ip=client_ip,message=msg")
+	profileAddr := flag.String("profile", "", "Serve net/http/pprof CPU, heap and other profiles on /debug/pprof/ at this address (e.g. :6060)")
+	httpAddr := flag.String("http", "", "Serve JSON stats on /stats and Prometheus metrics on /metrics at this address (e.g. :8080)")
+	alertLog := flag.String("alert-log", "", "Append every alert to this file as one JSON object per line, as an audit trail")
+	alertWebhook := flag.String("alert-webhook", "", "POST alerts as JSON to this URL")
+	slackWebhook := flag.String("slack-webhook", "", "Send alerts to this Slack incoming webhook URL")
+	slackBatch := flag.Duration("slack-batch", 5*time.Second, "Combine Slack alerts fired within this interval (0 sends each alert separately)")
+	multiline := flag.Bool("multiline", false, "Join continuation lines, such as stack trace frames, onto the entry before them")
+	continuationPattern := flag.String("continuation-pattern", "", "Regex matching continuation lines for -multiline (default: any line the line format does not match), e.g. '^(\\s|Caused by:)'")
+	latencyAlert := flag.Duration("latency-alert", 0, "Alert when p99 latency over the window stays above this, e.g. 500ms (needs -duration-pattern; 0 disables)")
+	latencyAlertTicks := flag.Int("latency-alert-ticks", analyzer.DefaultLatencyAlertTicks, "Consecutive seconds p99 latency must stay above -latency-alert before alerting")
+	parseAlert := flag.Float64("parse-alert", analyzer.DefaultParseAlertThreshold, "Alert when the parse success rate over -parse-alert-window falls below this percentage, e.g. after a log format change (0 disables)")
+	parseAlertWindow := flag.Duration("parse-alert-window", analyzer.DefaultParseAlertWindow, "Period the parse success rate is measured over for -parse-alert")
+	durationPattern := flag.String("duration-pattern", "", "Regex whose first group extracts a request duration in ms from messages, e.g. 'took (\\d+)ms'")
+	ipErrorShare := flag.Float64("ip-error-share", 0.5, "Alert when one IP suddenly produces more than this fraction of errors (0 disables)")
+	errorAlertThreshold := flag.Float64("error-alert-threshold", analyzer.DefaultErrorAlertThreshold, "Total errors/sec that raises a high error rate alert")
+	rateHigh := flag.Float64("rate-high", analyzer.DefaultRateHigh, "Entries/sec above which the window shrinks")
+	rateLow := flag.Float64("rate-low", analyzer.DefaultRateLow, "Entries/sec below which the window grows (must be below -rate-high)")
+	rateAlpha := flag.Float64("rate-alpha", analyzer.DefaultRateAlpha, "Smoothing factor (0-1] for the smoothed rate; higher follows the raw rate more closely")
+	smoothWindow := flag.Bool("smooth-window", false, "Adjust the window size from the smoothed rate instead of the raw rate")
+	resizeTicks := flag.Int("resize-ticks", analyzer.DefaultResizeTicks, "Consecutive seconds the rate must stay past -rate-high or -rate-low before the window is resized")
+	defaultPatterns := analyzer.DefaultPatternConfig()
+	patternThreshold := flag.Float64("pattern-threshold", defaultPatterns.ThresholdPercent, "Percentage increase that marks an error type as an emerging pattern")
+	patternRecent := flag.Int("pattern-recent", defaultPatterns.RecentSec, "Seconds of recent errors compared for emerging patterns")
+	patternPrevious := flag.Int("pattern-previous", defaultPatterns.PrevSec, "Seconds before the recent period that it is compared against")
+	patternMinCount := flag.Int("pattern-min-count", defaultPatterns.MinCount, "Fewest errors of a type in the recent period before it can be an emerging pattern")
+	spikeMultiplier := flag.Float64("spike-multiplier", defaultPatterns.SpikeMultiplier, "Error rate increase factor that boosts an error type's weight")
+	weightFactor := flag.Float64("weight-factor", defaultPatterns.WeightFactor, "Factor an error type's weight is multiplied by on a spike")
+	patternIdle := flag.Duration("pattern-idle-eviction", defaultPatterns.IdleEviction, "Forget an error type's pattern tracking once it has been idle this long and left the window (0 keeps all)")
+	weightHalfLife := flag.Duration("weight-half-life", defaultPatterns.WeightHalfLife, "Time for a spiked error type's extra weight to halve (0 never decays)")
+	sloTarget := flag.Float64("slo", 0, "Success-rate objective in percent (e.g. 99.9): report the error budget left and alert when it burns too fast (0 disables)")
+	anomalySigma := flag.Float64("anomaly-sigma", analyzer.DefaultAnomalySigma, "Standard deviations from the learned per-second rate that raise a rate anomaly alert (0 disables)")
+	staleAfter := flag.Duration("stale-after", analyzer.DefaultStaleAfter, "Alert when no valid entry has arrived for this long, and again when input resumes (0 disables)")
+	skewThreshold := flag.Duration("clock-skew-threshold", analyzer.DefaultSkewThreshold, "Alert when the median log timestamp of the latest entries is further than this from the wall clock, e.g. a wrong timezone (0 disables; skipped with -time-reference log)")
+	alertCooldown := flag.Duration("alert-cooldown", analyzer.DefaultAlertCooldown, "Minimum interval between repeats of the same alert (0 disables)")
+	minLevel := flag.String("min-level", "", "Skip entries less severe than this level (e.g. WARN) before they reach the window")
+	includePattern := flag.String("include", "", "Only analyze lines matching this regex, e.g. '/api/v2/'")
+	excludePattern := flag.String("exclude", "", "Skip lines matching this regex")
+	sinceText := flag.String("since", "", "Skip entries logged before this RFC3339 time, by their own timestamp")
+	untilText := flag.String("until", "", "Skip entries logged at or after this RFC3339 time, by their own timestamp")
+	filteredInRate := flag.Bool("filtered-in-rate", true, "Count entries skipped by -min-level, -include or -exclude in the processing rate")
+	rejectFile := flag.String("reject-file", "", "Append lines that fail to parse to this file, prefixed with the reason")
+	overflowName := flag.String("overflow", string(queue.PolicyBlock), "When the entry queue is full at its largest: block (slow the reader down), drop-oldest or drop-newest (dropped entries are counted)")
+	dropOnFull := flag.Bool("drop-on-full", false, "Same as -overflow drop-newest")
+	validateLines := flag.Int("validate", 0, "Dry run: parse the first N lines with the format settings, report how many parsed and why the rest failed, then exit")
+	validateMin := flag.Float64("validate-min", 90, "Parse success rate in percent below which -validate exits with status 2")
+	maxRuntime := flag.Duration("max-runtime", 0, "Shut down gracefully after this long, e.g. 30s (0 runs until interrupted)")
+	framingName := flag.String("framing", "newline", "How input is split into records: newline or varint (length-prefixed, as for protobuf streams)")
+	maxLineSize := flag.Int("max-line-size", reader.DefaultMaxLineSize, "Longest line in bytes that is parsed; longer lines are skipped and counted as invalid (too_long)")
+	parserWorkers := flag.Int("parser-workers", 1, "Number of goroutines parsing log lines in parallel")
+	heatmapOut := flag.String("heatmap-out", "", "Write errors by UTC hour of day to this file on shutdown, as JSON if it ends in .json and as CSV otherwise")
+	statsRotate := flag.Duration("stats-rotate", 0, "Reset entries processed, the peak rate and the since-start totals at every multiple of this interval (UTC-aligned, e.g. 1h on the hour), leaving the window untouched (0 never resets)")
+	benchmark := flag.Bool("benchmark", false, "On shutdown, print the tool's own throughput (entries processed per second of runtime), peak processing rate and CPU time to stderr")
+	summaryOut := flag.String("summary-out", "", "Write the final stats as JSON to this file on shutdown")
+	stateFile := flag.String("state-file", "", "Checkpoint entries processed, the peak rate and the since-start totals to this file periodically and on shutdown, for -resume")
+	stateInterval := flag.Duration("state-interval", analyzer.DefaultStateInterval, "How often -state-file is written while running (0 only on shutdown)")
+	resume := flag.Bool("resume", false, "Continue the since-start totals from -state-file, if it exists, instead of starting from zero")
+	levelList := flag.String("levels", strings.Join(reader.DefaultLevels, ","), "Comma-separated log levels to accept, in display order")
+	var errorPatterns stringList
+	flag.Var(&errorPatterns, "error-pattern", "Regex extracting the error type from ERROR messages (first group, or whole match); repeat to try several in order")
+	fingerprintSpec := flag.String("fingerprint", "", "Comma-separated rules replacing variable parts of error types so similar errors group together: uuid, ip, hex, digits")
+	groupBy := flag.String("group-by", "", "Report the most frequent values in the window of this dimension: level, ip, error_type or a named group of -format, e.g. status for (?P<status>\\d+)")
+	compactWindow := flag.Bool("compact-window", false, "Store only the fields needed for counts and rates in the sliding window")
+	timeRefName := flag.String("time-reference", "wall", "Measure the window and error rates back from the wall clock (wall) or from the newest entry's timestamp (log, for historical logs)")
+	replay := flag.Bool("replay", false, "Replay the input at the pace it was logged, using the gaps between timestamps")
+	replaySpeed := flag.Float64("replay-speed", 1, "Speed-up factor for -replay (e.g. 10 replays ten times faster)")
+	rateCSVPath := flag.String("rate-csv", "", "Append the entry count of every rate bucket (see -rate-bucket) as timestamp,count rows to this CSV file")
+	rateBucket := flag.Duration("rate-bucket", analyzer.DefaultRateBucket, "Interval entries are counted in for rates: a divisor of 1s down to 10ms for smoother rates on busy feeds, or 2s, 5s or 10s for less noise on quiet ones")
+	topN := flag.Int("top-n", display.DefaultTopN, "How many top errors, emerging patterns and sources the report lists (up to 10)")
+	alertHistory := flag.Int("alert-history", display.DefaultAlertHistory, "Alerts kept by the display")
+	alertShow := flag.Int("alert-show", display.DefaultAlertShow, "Most recent alerts shown in the report")
+	alertMaxAge := flag.Duration("alert-max-age", 0, "Drop alerts older than this from the report, so a quiet period clears them (0 keeps them)")
+	refresh := flag.Duration("refresh", 0, "Minimum interval between report renders; faster stats updates are coalesced (0 renders every update)")
+	noClear := flag.Bool("no-clear", false, "Print only changed report lines instead of redrawing the screen (for tmux or redirected output)")
+	colorName := flag.String("color", "auto", "Colorize the report: auto (only on a terminal), always or never")
+	outputName := flag.String("output", "text", "Output mode: text (live report), json (one JSON object per line) or oneline (one key=value status line per update)")
+	var timeLayouts stringList
+	flag.Var(&timeLayouts, "time-layout", "Go time layout for log timestamps; repeat to try several in order")
+	timezone := flag.String("timezone", "", "IANA zone, e.g. Europe/Berlin, of log timestamps without an offset (default UTC)")
+	displayTimezone := flag.Bool("display-timezone", false, "Show report and alert times in -timezone instead of UTC")
+	format := flag.String("format", "", "Custom log line regex with named groups (?P<timestamp>), (?P<level>), (?P<ip>), (?P<message>), or syslog, syslog-rfc3164 or syslog-rfc5424")
+	configPath := flag.String("config", "", "JSON file of flag values keyed by flag name, e.g. {\"top-n\": 5}; flags given on the command line override it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [logfile...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	// Create channels for communication between components
-	logChan := make(chan models.LogEntry, LogChannelSize)
-	statsChan := make(chan *models.LogStats, StatsChannelSize)
-	alertChan := make(chan models.Alert, AlertChannelSize)
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -config: %v\n", err)
+			os.Exit(1)
+		}
+		unknown, err := cfg.apply(flag.CommandLine)
+		for _, key := range unknown {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring unknown key %q in %s\n", key, *configPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Compile the custom log format up front so a bad pattern fails fast
+	var formatRegex *regexp.Regexp
+	syslogFormat, isSyslog := reader.ParseSyslogFormat(*format)
+	if *format != "" && !isSyslog {
+		var err error
+		formatRegex, err = regexp.Compile(*format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -format regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var errorRegexes []*regexp.Regexp
+	for _, pattern := range errorPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -error-pattern regex: %v\n", err)
+			os.Exit(1)
+		}
+		errorRegexes = append(errorRegexes, re)
+	}
+
+	if *minLevel != "" {
+		if _, err := models.ParseLevel(*minLevel); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -min-level: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var includeRegex, excludeRegex *regexp.Regexp
+	if *includePattern != "" {
+		var err error
+		includeRegex, err = regexp.Compile(*includePattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -include regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *excludePattern != "" {
+		var err error
+		excludeRegex, err = regexp.Compile(*excludePattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -exclude regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var location *time.Location
+	if *timezone != "" {
+		var err error
+		location, err = time.LoadLocation(*timezone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -timezone: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *displayTimezone && location == nil {
+		fmt.Fprintln(os.Stderr, "Invalid -display-timezone: needs -timezone")
+		os.Exit(1)
+	}
+	if *resume && *stateFile == "" {
+		fmt.Fprintln(os.Stderr, "Invalid -resume: needs -state-file")
+		os.Exit(1)
+	}
+
+	var since, until time.Time
+	if *sinceText != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, *sinceText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -since: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *untilText != "" {
+		var err error
+		until, err = time.Parse(time.RFC3339, *untilText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -until: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && !until.After(since) {
+		fmt.Fprintln(os.Stderr, "Invalid -until: must be after -since")
+		os.Exit(1)
+	}
+
+	var durationRegex *regexp.Regexp
+	if *durationPattern != "" {
+		var err error
+		durationRegex, err = regexp.Compile(*durationPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -duration-pattern regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var continuationRegex *regexp.Regexp
+	if *continuationPattern != "" {
+		var err error
+		continuationRegex, err = regexp.Compile(*continuationPattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -continuation-pattern regex: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *multiline && *jsonMode {
+		fmt.Fprintln(os.Stderr, "Invalid -multiline: JSON lines cannot be joined, so -multiline cannot be combined with -json")
+		os.Exit(1)
+	}
+
+	outputMode, err := display.ParseOutputMode(*outputName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -output: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *dropOnFull {
+		*overflowName = string(queue.PolicyDropNewest)
+	}
+	overflow, err := queue.ParsePolicy(*overflowName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -overflow: %v\n", err)
+		os.Exit(1)
+	}
+
+	framing, err := reader.ParseFraming(*framingName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -framing: %v\n", err)
+		os.Exit(1)
+	}
+	if framing == reader.FramingVarint && *follow {
+		fmt.Fprintln(os.Stderr, "Invalid -framing: varint framing cannot be combined with -follow")
+		os.Exit(1)
+	}
+	if *listenAddr != "" {
+		if _, _, err := reader.ParseListenAddr(*listenAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -listen: %v\n", err)
+			os.Exit(1)
+		}
+		switch {
+		case flag.NArg() > 0:
+			fmt.Fprintln(os.Stderr, "Invalid -listen: cannot be combined with log files")
+			os.Exit(1)
+		case *once:
+			fmt.Fprintln(os.Stderr, "Invalid -listen: network input never ends, so -listen cannot be combined with -once")
+			os.Exit(1)
+		case *multiline:
+			fmt.Fprintln(os.Stderr, "Invalid -listen: lines from different clients interleave, so -listen cannot be combined with -multiline")
+			os.Exit(1)
+		}
+	}
+	if *once && *follow {
+		fmt.Fprintln(os.Stderr, "Invalid -once: a followed file never ends, so -once cannot be combined with -follow")
+		os.Exit(1)
+	}
+
+	colorMode, err := display.ParseColorMode(*colorName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -color: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeRef, err := analyzer.ParseTimeReference(*timeRefName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -time-reference: %v\n", err)
+		os.Exit(1)
+	}
+
+	var levels []string
+	for _, level := range strings.Split(*levelList, ",") {
+		if level = strings.ToUpper(strings.TrimSpace(level)); level != "" {
+			levels = append(levels, level)
+		}
+	}
+
+	fingerprintRules, err := reader.ParseFingerprintRules(*fingerprintSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -fingerprint: %v\n", err)
+		os.Exit(1)
+	}
+
+	jsonFields, err := reader.ParseJSONFields(*jsonFieldSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -json-fields: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := pipeline.DefaultOptions()
+	opts.Paths = flag.Args()
+	opts.Listen = *listenAddr
+	opts.Follow = *follow
+	opts.StopAtEOF = *once
+	opts.Framing = framing
+	opts.ParserWorkers = *parserWorkers
+	opts.MaxLineSize = *maxLineSize
+	opts.Overflow = overflow
+	opts.RejectFile = *rejectFile
+	opts.Replay = *replay
+	opts.ReplaySpeed = *replaySpeed
+	opts.Format = formatRegex
+	opts.Syslog = syslogFormat
+	opts.JSON = *jsonMode
+	opts.Multiline = *multiline
+	opts.Continuation = continuationRegex
+	opts.JSONFields = jsonFields
+	opts.TimeLayouts = timeLayouts
+	opts.Timezone = location
+	opts.DisplayTimezone = *displayTimezone
+	opts.Levels = levels
+	opts.ErrorPatterns = errorRegexes
+	opts.Fingerprint = fingerprintRules
+	opts.DurationPattern = durationRegex
+	opts.MinLevel = *minLevel
+	opts.Include = includeRegex
+	opts.Exclude = excludeRegex
+	opts.Since = since
+	opts.Until = until
+	opts.BufferSize = *bufferSize
+	opts.BufferGrowAt = *bufferGrowAt
+	opts.BufferGrowFactor = *bufferGrowFactor
+	opts.BufferShrinkBelow = *bufferShrinkBelow
+	opts.IPErrorShare = *ipErrorShare
+	opts.ErrorAlertThreshold = *errorAlertThreshold
+	opts.RateHigh = *rateHigh
+	opts.RateLow = *rateLow
+	opts.RateAlpha = *rateAlpha
+	opts.SmoothWindow = *smoothWindow
+	opts.ResizeTicks = *resizeTicks
+	opts.Patterns = analyzer.PatternConfig{
+		ThresholdPercent: *patternThreshold,
+		RecentSec:        *patternRecent,
+		PrevSec:          *patternPrevious,
+		MinCount:         *patternMinCount,
+		SpikeMultiplier:  *spikeMultiplier,
+		WeightFactor:     *weightFactor,
+		WeightHalfLife:   *weightHalfLife,
+		IdleEviction:     *patternIdle,
+	}
+	opts.AlertCooldown = *alertCooldown
+	opts.AnomalySigma = *anomalySigma
+	opts.StaleAfter = *staleAfter
+	opts.RateBucket = *rateBucket
+	opts.SkewThreshold = *skewThreshold
+	opts.LatencyAlert = *latencyAlert
+	opts.LatencyAlertTicks = *latencyAlertTicks
+	opts.ParseAlert = *parseAlert
+	opts.ParseAlertWindow = *parseAlertWindow
+	opts.StatsRotate = *statsRotate
+	opts.SLO = *sloTarget
+	opts.FilteredInRate = *filteredInRate
+	opts.CompactWindow = *compactWindow
+	opts.GroupBy = *groupBy
+	opts.TimeReference = timeRef
+	opts.RateCSV = *rateCSVPath
+	opts.StateFile = *stateFile
+	opts.StateInterval = *stateInterval
+	opts.Resume = *resume
+	opts.Output = outputMode
+	opts.Color = colorMode
+	opts.NoClear = *noClear
+	opts.Refresh = *refresh
+	opts.AlertHistory = *alertHistory
+	opts.AlertShow = *alertShow
+	opts.AlertMaxAge = *alertMaxAge
+	opts.Once = *once
+	opts.TopN = *topN
+	opts.HTTPAddr = *httpAddr
+	opts.ProfileAddr = *profileAddr
+	opts.AlertLog = *alertLog
+	opts.AlertWebhook = *alertWebhook
+	opts.SlackWebhook = *slackWebhook
+	opts.SlackBatch = *slackBatch
+	opts.Debug = *debugMode
+	opts.OnShutdown = func() {
+		// Status messages go to stderr so they never mix with -output json
+		fmt.Fprintln(os.Stderr, "\nShutting down gracefully...")
+	}
+
+	if *validateLines > 0 {
+		if *validateMin < 0 || *validateMin > 100 {
+			fmt.Fprintln(os.Stderr, "Invalid -validate-min: must be between 0 and 100")
+			os.Exit(1)
+		}
+		report, err := pipeline.Validate(opts, *validateLines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set up: %v\n", err)
+			os.Exit(1)
+		}
+		printValidation(report)
+		if report.SuccessRate() < *validateMin {
+			fmt.Fprintf(os.Stderr, "Parse success rate %.1f%% is below -validate-min %g%%\n", report.SuccessRate(), *validateMin)
+			os.Exit(2)
+		}
+		return
+	}
+
+	logPipeline, err := pipeline.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Create components
-	logReader := reader.NewReader(logChan, *debugMode)
-	logAnalyzer := analyzer.NewAnalyzer(logChan, statsChan, alertChan, *debugMode, *bufferSize)
-	logDisplay := display.NewDisplay(statsChan, alertChan)
+	// The root context is cancelled by SIGINT or SIGTERM, or once a
+	// bounded run's time is up
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxRuntime)
+		defer cancel()
+	}
 
-	// Start components
-	logReader.Start()
-	logAnalyzer.Start()
-	logDisplay.Start()
+	// SIGUSR1 dumps the window contents to stderr, leaving the display on
+	// stdout alone
+	dumpSignals := make(chan os.Signal, 1)
+	signal.Notify(dumpSignals, syscall.SIGUSR1)
+	defer signal.Stop(dumpSignals)
+	go func() {
+		for range dumpSignals {
+			printWindowSnapshot(logPipeline.WindowSnapshot())
+		}
+	}()
 
-	// Set up graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	started := time.Now()
+	finalStats, err := logPipeline.Run(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to run: %v\n", err)
+		os.Exit(1)
+	}
+	if *benchmark {
+		printBenchmark(finalStats, time.Since(started))
+	}
 
-	<-sigChan
-	fmt.Println("\nShutting down gracefully...")
+	if *summaryOut != "" {
+		if err := writeSummary(*summaryOut, finalStats); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write summary: %v\n", err)
+		}
+	}
 
-	// Stop components in reverse order
-	logDisplay.Stop()
-	logAnalyzer.Stop()
-	logReader.Stop()
+	if *heatmapOut != "" {
+		if err := analyzer.WriteHeatmap(*heatmapOut, finalStats.Lifetime); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write heatmap: %v\n", err)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutdown complete.")
+}
+
+// printBenchmark writes the throughput the tool achieved to stderr. Unlike
+// the current rate, which follows the input, it divides everything
+// processed by the wall-clock runtime, so a huge file measures capacity.
+func printBenchmark(stats *models.LogStats, runtime time.Duration) {
+	seconds := runtime.Seconds()
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Benchmark ===\n")
+	fmt.Fprintf(&b, "Runtime:      %s\n", runtime.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Processed:    %d entries (%d lines, %d dropped)\n", stats.EntriesProcessed, stats.TotalLines, stats.DroppedEntries)
+	fmt.Fprintf(&b, "Throughput:   %.0f entries/sec (%.0f lines/sec)\n",
+		float64(stats.EntriesProcessed)/seconds, float64(stats.TotalLines)/seconds)
+	fmt.Fprintf(&b, "Peak rate:    %.0f entries/sec\n", stats.PeakRate)
+
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err == nil {
+		user := time.Duration(usage.Utime.Nano())
+		system := time.Duration(usage.Stime.Nano())
+		fmt.Fprintf(&b, "CPU time:     %s user, %s system (%.1f cores busy)\n",
+			user.Round(time.Millisecond), system.Round(time.Millisecond), (user+system).Seconds()/seconds)
+	}
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// writeSummary writes the final stats to path as indented JSON
+func writeSummary(path string, stats *models.LogStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printWindowSnapshot writes a window dump to stderr in a single write, so
+// it is not interleaved with other output
+func printWindowSnapshot(snap analyzer.WindowSnapshot) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Window snapshot (%s window, %d entries) ===\n", snap.Duration, snap.Total)
+	if !snap.Oldest.IsZero() {
+		fmt.Fprintf(&b, "Oldest: %s\nNewest: %s (span %s)\n",
+			snap.Oldest.Format(time.RFC3339Nano), snap.Newest.Format(time.RFC3339Nano),
+			snap.Newest.Sub(snap.Oldest))
+	}
+	writeCounts(&b, "Levels", snap.LevelCounts)
+	writeCounts(&b, "Error types", snap.ErrorCounts)
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// writeCounts writes counts under a heading, largest first
+func writeCounts(b *strings.Builder, heading string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(b, "%s:\n", heading)
+	for _, k := range keys {
+		fmt.Fprintf(b, "  %-28s %d\n", k, counts[k])
+	}
+}
+
+// printValidation prints a -validate report: the parse success rate, the
+// rejected lines by reason and a few examples of each
+func printValidation(report *pipeline.ValidationReport) {
+	fmt.Printf("Validated %d lines: %d parsed (%.1f%%), %d rejected\n",
+		report.Lines, report.Parsed, report.SuccessRate(), report.Lines-report.Parsed)
+
+	if len(report.Rejects) > 0 {
+		reasons := make([]string, 0, len(report.Rejects))
+		for reason := range report.Rejects {
+			reasons = append(reasons, reason)
+		}
+		sort.Slice(reasons, func(i, j int) bool {
+			if report.Rejects[reasons[i]] != report.Rejects[reasons[j]] {
+				return report.Rejects[reasons[i]] > report.Rejects[reasons[j]]
+			}
+			return reasons[i] < reasons[j]
+		})
+
+		fmt.Println("\nRejected by reason:")
+		for _, reason := range reasons {
+			fmt.Printf("  %-14s %d\n", reason, report.Rejects[reason])
+		}
+	}
+
+	if len(report.Examples) > 0 {
+		fmt.Println("\nExample entries:")
+		for _, entry := range report.Examples {
+			fmt.Printf("  timestamp=%s level=%s ip=%q error_type=%q message=%q\n",
+				entry.Timestamp.Format(time.RFC3339Nano), entry.Level, entry.IP, entry.ErrorType, entry.Message)
+		}
+	}
+
+	if len(report.Rejected) > 0 {
+		fmt.Println("\nExample rejected lines:")
+		for _, entry := range report.Rejected {
+			line := entry.OriginalLog
+			if len(line) > 120 {
+				line = line[:120] + "..."
+			}
+			fmt.Printf("  %-14s %s\n", entry.Reject, line)
+		}
+	}
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
 
-	fmt.Println("Shutdown complete.")
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
@@ -4,73 +4,303 @@
 package models
 
 import (
-	"sync"
+	"fmt"
+	"strings"
 	"time"
 )
 
+// Severity ranks how important a log level or alert is
+type Severity int
+
+// Severities in increasing order. Warning is the zero value, so an alert
+// raised without a severity is neither hidden nor escalated.
+const (
+	SeverityDebug Severity = iota - 2
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// severityNames are the lower-case names of each severity, used in JSON
+var severityNames = map[Severity]string{
+	SeverityDebug:    "debug",
+	SeverityInfo:     "info",
+	SeverityWarning:  "warning",
+	SeverityError:    "error",
+	SeverityCritical: "critical",
+}
+
+// String returns the severity's lower-case name
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the severity by name, e.g. "warning"
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// LevelSeverity maps a log level name to its severity. Unrecognized levels
+// (e.g. TRACE) rank as debug.
+func LevelSeverity(level string) Severity {
+	switch strings.ToUpper(level) {
+	case "FATAL", "CRITICAL", "PANIC":
+		return SeverityCritical
+	case "ERROR":
+		return SeverityError
+	case "WARN", "WARNING":
+		return SeverityWarning
+	case "INFO":
+		return SeverityInfo
+	default:
+		return SeverityDebug
+	}
+}
+
+// ParseLevel maps a level name to its severity like LevelSeverity, but
+// fails on names it does not know instead of ranking them as debug, e.g.
+// a misspelled minimum level
+func ParseLevel(level string) (Severity, error) {
+	switch strings.ToUpper(level) {
+	case "DEBUG", "TRACE":
+		return SeverityDebug, nil
+	case "INFO", "WARN", "WARNING", "ERROR", "FATAL", "CRITICAL", "PANIC":
+		return LevelSeverity(level), nil
+	default:
+		return 0, fmt.Errorf("unknown level %q, expected one of TRACE, DEBUG, INFO, WARN, WARNING, ERROR, CRITICAL, FATAL or PANIC", level)
+	}
+}
+
 // LogEntry represents a parsed log entry
 type LogEntry struct {
 	Timestamp   time.Time
 	Level       string
 	IP          string
 	Message     string
-	ErrorType   string // For ERROR logs
-	IsValid     bool   // Flag for valid parsing
-	OriginalLog string // Original log string
+	ErrorType   string  // For ERROR logs
+	IsValid     bool    // Flag for valid parsing
+	OriginalLog string  // Original log string
+	DurationMs  float64 // Request duration extracted from the message
+	HasDuration bool    // Set when DurationMs was extracted
+	Filtered    bool    // Valid, but rejected by the reader's level or line filters
+	Reject      string  // Why an invalid entry failed to parse, one of the Reject* reasons
+
+	Fields map[string]string // Other named groups of a custom line format, e.g. method or status
 }
 
-// LogStats represents statistics for logs
+// Reasons a line fails to parse, set on LogEntry.Reject
+const (
+	RejectEmpty        = "empty"
+	RejectRegexMiss    = "regex_miss"
+	RejectBadJSON      = "bad_json"
+	RejectBadSyslog    = "bad_syslog" // No valid <PRI> priority at the start of a syslog line
+	RejectBadTimestamp = "bad_timestamp"
+	RejectUnknownLevel = "unknown_level"
+	RejectBadRecord    = "bad_record" // A custom decoder could not decode the record
+	RejectTooLong      = "too_long"   // Longer than the reader's maximum line size, skipped unparsed
+)
+
+// LogStats represents statistics for logs. It has no lock of its own: the
+// analyzer guards its working copy with its own mutex and only ever hands
+// out clones (see Clone), which it never touches again. A clone may be read
+// from any goroutine without locking, but is shared between every receiver
+// of a stats update, so it must not be modified.
 type LogStats struct {
-	EntriesProcessed  int
-	CurrentRate       float64
-	PeakRate          float64
-	WindowSize        int // in seconds
-	LevelCounts       map[string]int
-	ErrorCounts       map[string]int
-	ErrorRates        map[string]float64
-	EmergingPatterns  map[string]float64 // pattern -> percentage increase
-	SkippedEntries    int
-	LastUpdated       time.Time
-	mux               sync.RWMutex
-	EmergingPatternHistory []EmergingPatternEvent
-	PreviousWindowSize int // Track the previous window size for display
+	EntriesProcessed       int                    `json:"entries_processed"`
+	CurrentRate            float64                `json:"current_rate"`
+	SmoothedRate           float64                `json:"smoothed_rate"` // EWMA of CurrentRate
+	PeakRate               float64                `json:"peak_rate"`
+	BaselineRate           float64                `json:"baseline_rate"`   // Mean entries/sec learned for anomaly detection, 0 while learning
+	BaselineStdDev         float64                `json:"baseline_stddev"` // Standard deviation of BaselineRate
+	WindowSize             int                    `json:"window_size"`     // in seconds
+	LevelCounts            map[string]int         `json:"level_counts"`
+	LevelRates             map[string]float64     `json:"level_rates"` // Entries/sec per level over the window
+	ErrorCounts            map[string]int         `json:"error_counts"`
+	ErrorRates             map[string]float64     `json:"error_rates"`
+	EmergingPatterns       map[string]float64     `json:"emerging_patterns"` // pattern -> percentage increase
+	SkippedEntries         int                    `json:"skipped_entries"`
+	TotalLines             int                    `json:"total_lines"`      // Lines that reached the analyzer, valid or not
+	FilteredEntries        int                    `json:"filtered_entries"` // Valid entries rejected by the level or line filters
+	RejectCounts           map[string]int         `json:"reject_counts"`    // Skipped entries by Reject reason
+	DroppedEntries         int                    `json:"dropped_entries"`  // Entries dropped because the pipeline was backed up
+	BufferSize             int                    `json:"buffer_size"`      // Capacity of the queue between reader and analyzer
+	BufferUsed             int                    `json:"buffer_used"`      // Entries waiting in that queue
+	BufferResized          bool                   `json:"buffer_resized"`   // Set when BufferSize differs from the -buffer value
+	LastUpdated            time.Time              `json:"last_updated"`
+	EmergingPatternHistory []EmergingPatternEvent `json:"emerging_pattern_history"`
+	PreviousWindowSize     int                    `json:"previous_window_size"` // Track the previous window size for display
+	LatencySamples         int                    `json:"latency_samples"`      // Entries with a duration in the window
+	LatencyP50             float64                `json:"latency_p50_ms"`
+	LatencyP95             float64                `json:"latency_p95_ms"`
+	LatencyP99             float64                `json:"latency_p99_ms"`
+	TopIPs                 []IPCount              `json:"top_ips"`       // Busiest source IPs in the window
+	UniqueIPs              int                    `json:"unique_ips"`    // Distinct source IPs in the window
+	WarningRate            float64                `json:"warning_rate"`  // WARN entries/sec over the window
+	InterArrival           []HistogramBucket      `json:"inter_arrival"` // Gaps between consecutive entries
+	RateHistory            []int                  `json:"rate_history"`  // Entries per second over the last minute, oldest first
+	TopErrors              []WeightedError        `json:"top_errors"`    // Error types ranked by weighted count
+	Lifetime               LifetimeStats          `json:"lifetime"`      // Totals since start, independent of the window
+	RateTiers              []RateTier             `json:"rate_tiers"`    // Entry counts at 1s, 10s and 60s resolution
+	SLO                    SLOStats               `json:"slo"`           // Error budget, zero unless an SLO target is set
+
+	GroupBy   string       `json:"group_by,omitempty"`   // Field entries are grouped by, see TopGroups
+	TopGroups []FieldCount `json:"top_groups,omitempty"` // Most frequent values of GroupBy in the window
+}
+
+// SLOStats is the error budget against a success-rate objective. ERROR and
+// critical entries count as failures.
+type SLOStats struct {
+	Target          float64 `json:"target"` // Success-rate objective in percent
+	Total           int     `json:"total"`  // Valid entries since start
+	Failed          int     `json:"failed"`
+	SuccessRate     float64 `json:"success_rate"`
+	BudgetRemaining float64 `json:"budget_remaining"` // Percent of the error budget left, negative once overspent
+	FastBurnRate    float64 `json:"fast_burn_rate"`   // Budget burn over the last 5 minutes, 1 spends it exactly
+	SlowBurnRate    float64 `json:"slow_burn_rate"`   // Budget burn over the last hour
+}
+
+// RateTier is the entry count series at one resolution, oldest first
+type RateTier struct {
+	ResolutionSec int   `json:"resolution_sec"`
+	Counts        []int `json:"counts"`
+}
+
+// LifetimeStats aggregates every valid entry since the analyzer started
+type LifetimeStats struct {
+	Since          time.Time      `json:"since"`
+	LevelCounts    map[string]int `json:"level_counts"`
+	ErrorCounts    map[string]int `json:"error_counts"`
+	RateSeries     []int          `json:"rate_series"`     // Entries per point, oldest first
+	RateResolution int            `json:"rate_resolution"` // Seconds covered by each point of RateSeries
+
+	// ERROR and critical entries by the UTC hour of day of their timestamp,
+	// in total and per error type
+	ErrorsByHour     [24]int            `json:"errors_by_hour"`
+	ErrorTypesByHour map[string][24]int `json:"error_types_by_hour"`
+
+	UniqueIPs int `json:"unique_ips"` // Distinct source IPs, a HyperLogLog estimate within about 1%
+}
+
+// ParseSuccessRate returns the percentage of lines that parsed, or 100
+// before any line has been read
+func (s *LogStats) ParseSuccessRate() float64 {
+	if s.TotalLines == 0 {
+		return 100
+	}
+	return 100 * float64(s.TotalLines-s.SkippedEntries) / float64(s.TotalLines)
+}
+
+// WeightedError is an error type's count in the window with the weight
+// applied by pattern tracking (1 unless its rate recently spiked)
+type WeightedError struct {
+	Type   string  `json:"type"`
+	Count  int     `json:"count"`
+	Weight float64 `json:"weight"`
+}
+
+// HistogramBucket is one labeled bucket of a histogram
+type HistogramBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// IPCount is the number of entries from a source IP in the window
+type IPCount struct {
+	IP    string `json:"ip"`
+	Count int    `json:"count"`
+}
+
+// FieldCount is the number of entries in the window with a field value
+type FieldCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
 // EmergingPatternEvent tracks history of pattern spikes
 type EmergingPatternEvent struct {
-	Pattern     string
-	StartTime   time.Time
-	EndTime     time.Time
-	PeakChange  float64
-	Description string
+	Pattern     string    `json:"pattern"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	PeakChange  float64   `json:"peak_change"`
+	Description string    `json:"description"`
 }
 
-
-// Alert represents an alert message
+// Alert represents an alert message. Severity defaults to SeverityWarning
+// when left unset.
 type Alert struct {
-	Timestamp time.Time
-	Message   string
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Severity  Severity  `json:"severity"`
 }
 
 // NewLogStats creates a new LogStats instance
 func NewLogStats() *LogStats {
 	stats := &LogStats{
-			LevelCounts:      make(map[string]int),
-			ErrorCounts:      make(map[string]int),
-			ErrorRates:       make(map[string]float64),
-			EmergingPatterns: make(map[string]float64),
-			WindowSize:       60, // Default 60-second window
-			PreviousWindowSize: 60, // Initialize same as starting window
-			LastUpdated:      time.Now(),
-			EmergingPatternHistory: make([]EmergingPatternEvent, 0, 5),
+		LevelCounts:            make(map[string]int),
+		LevelRates:             make(map[string]float64),
+		ErrorCounts:            make(map[string]int),
+		ErrorRates:             make(map[string]float64),
+		EmergingPatterns:       make(map[string]float64),
+		RejectCounts:           make(map[string]int),
+		WindowSize:             60, // Default 60-second window
+		PreviousWindowSize:     60, // Initialize same as starting window
+		LastUpdated:            time.Now(),
+		EmergingPatternHistory: make([]EmergingPatternEvent, 0, 5),
 	}
-	
+
 	return stats
 }
 
+// Clone returns a deep copy of the stats, with every map and slice copied so
+// the clone can be read while the original keeps being updated. The caller
+// must hold whatever lock guards s.
+func (s *LogStats) Clone() *LogStats {
+	c := *s
+	c.LevelCounts = copyMap(s.LevelCounts)
+	c.LevelRates = copyMap(s.LevelRates)
+	c.ErrorCounts = copyMap(s.ErrorCounts)
+	c.ErrorRates = copyMap(s.ErrorRates)
+	c.EmergingPatterns = copyMap(s.EmergingPatterns)
+	c.RejectCounts = copyMap(s.RejectCounts)
+	c.EmergingPatternHistory = copySlice(s.EmergingPatternHistory)
+	c.TopIPs = copySlice(s.TopIPs)
+	c.TopGroups = copySlice(s.TopGroups)
+	c.InterArrival = copySlice(s.InterArrival)
+	c.RateHistory = copySlice(s.RateHistory)
+	c.TopErrors = copySlice(s.TopErrors)
+	c.Lifetime.LevelCounts = copyMap(s.Lifetime.LevelCounts)
+	c.Lifetime.ErrorCounts = copyMap(s.Lifetime.ErrorCounts)
+	c.Lifetime.RateSeries = copySlice(s.Lifetime.RateSeries)
+	c.Lifetime.ErrorTypesByHour = copyMap(s.Lifetime.ErrorTypesByHour)
+	c.RateTiers = copyRateTiers(s.RateTiers)
+	return &c
+}
+
+// copyRateTiers returns a copy of tiers including their counts
+func copyRateTiers(tiers []RateTier) []RateTier {
+	result := make([]RateTier, len(tiers))
+	for i, tier := range tiers {
+		result[i] = RateTier{ResolutionSec: tier.ResolutionSec, Counts: copySlice(tier.Counts)}
+	}
+	return result
+}
+
+// copyMap returns a copy of m, never nil so clones can be written to
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	result := make(map[K]V, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
 
-// Thread-safe methods for LogStats
-func (s *LogStats) Lock()    { s.mux.Lock() }
-func (s *LogStats) Unlock()  { s.mux.Unlock() }
-func (s *LogStats) RLock()   { s.mux.RLock() }
-func (s *LogStats) RUnlock() { s.mux.RUnlock() }
+// copySlice returns a copy of s, never nil so clones encode as [] in JSON
+func copySlice[T any](s []T) []T {
+	result := make([]T, len(s))
+	copy(result, s)
+	return result
+}
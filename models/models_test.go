@@ -0,0 +1,181 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fill sets every field reachable from v to a non-zero value, giving maps
+// and slices one element each, so new fields are covered automatically
+func fill(v reflect.Value, next *int) {
+	*next++
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(*next))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(*next))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(*next) + 0.5)
+	case reflect.String:
+		v.SetString("value" + string(rune('a'+*next%26)))
+	case reflect.Struct:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(time.Unix(int64(*next), 0).UTC()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i), next)
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i), next)
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0), next)
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key := reflect.New(v.Type().Key()).Elem()
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fill(key, next)
+		fill(elem, next)
+		v.SetMapIndex(key, elem)
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), next)
+	default:
+		panic("fill: unhandled kind " + v.Kind().String())
+	}
+}
+
+// checkNoSharing fails if any map, slice or pointer reachable from
+// original is also reachable from clone
+func checkNoSharing(t *testing.T, path string, original, clone reflect.Value) {
+	t.Helper()
+
+	switch original.Kind() {
+	case reflect.Struct:
+		if original.Type() == timeType {
+			return
+		}
+		for i := 0; i < original.NumField(); i++ {
+			if original.Type().Field(i).IsExported() {
+				checkNoSharing(t, path+"."+original.Type().Field(i).Name, original.Field(i), clone.Field(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < original.Len(); i++ {
+			checkNoSharing(t, path, original.Index(i), clone.Index(i))
+		}
+	case reflect.Slice:
+		if original.Pointer() == clone.Pointer() {
+			t.Errorf("%s: clone shares the slice", path)
+			return
+		}
+		for i := 0; i < original.Len(); i++ {
+			checkNoSharing(t, path+"[]", original.Index(i), clone.Index(i))
+		}
+	case reflect.Map:
+		if original.Pointer() == clone.Pointer() {
+			t.Errorf("%s: clone shares the map", path)
+			return
+		}
+		for _, key := range original.MapKeys() {
+			checkNoSharing(t, path+"[]", original.MapIndex(key), clone.MapIndex(key))
+		}
+	case reflect.Pointer:
+		if !original.IsNil() && original.Pointer() == clone.Pointer() {
+			t.Errorf("%s: clone shares the pointer", path)
+		}
+	}
+}
+
+func TestCloneCopiesEveryField(t *testing.T) {
+	original := &LogStats{}
+	next := 0
+	fill(reflect.ValueOf(original).Elem(), &next)
+
+	clone := original.Clone()
+
+	if !reflect.DeepEqual(original, clone) {
+		t.Fatalf("clone differs from the original:\n got %+v\nwant %+v", clone, original)
+	}
+	checkNoSharing(t, "LogStats", reflect.ValueOf(original).Elem(), reflect.ValueOf(clone).Elem())
+}
+
+func TestCloneOfNewStats(t *testing.T) {
+	original := NewLogStats()
+	clone := original.Clone()
+
+	clone.LevelCounts["ERROR"]++
+	clone.Lifetime.ErrorCounts["timeout"]++
+	if original.LevelCounts["ERROR"] != 0 || original.Lifetime.ErrorCounts["timeout"] != 0 {
+		t.Error("writing to the clone changed the original")
+	}
+}
+
+func TestClonePreservesWindowAdjustment(t *testing.T) {
+	original := NewLogStats()
+	original.PreviousWindowSize = original.WindowSize
+	original.WindowSize = 40
+
+	clone := original.Clone()
+	if clone.WindowSize != 40 || clone.PreviousWindowSize != 60 {
+		t.Errorf("clone has window %ds, previous %ds, want 40s and 60s", clone.WindowSize, clone.PreviousWindowSize)
+	}
+
+	// The next cycle settles the size on the original only
+	original.PreviousWindowSize = original.WindowSize
+	if clone.PreviousWindowSize != 60 {
+		t.Errorf("clone previous window changed to %ds with the original", clone.PreviousWindowSize)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  Severity
+	}{
+		{"TRACE", SeverityDebug},
+		{"debug", SeverityDebug},
+		{"INFO", SeverityInfo},
+		{"WARN", SeverityWarning},
+		{"Warning", SeverityWarning},
+		{"ERROR", SeverityError},
+		{"FATAL", SeverityCritical},
+		{"critical", SeverityCritical},
+		{"PANIC", SeverityCritical},
+	}
+	for _, tt := range tests {
+		if got, err := ParseLevel(tt.level); err != nil || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %s, %v, want %s", tt.level, got, err, tt.want)
+		}
+	}
+
+	for _, level := range []string{"WARNN", "NOTICE", ""} {
+		if _, err := ParseLevel(level); err == nil {
+			t.Errorf("ParseLevel(%q) accepted an unknown level", level)
+		}
+	}
+}
+
+func TestAlertSeverityDefaultsToWarning(t *testing.T) {
+	var alert Alert
+	if alert.Severity != SeverityWarning {
+		t.Errorf("unset alert severity is %s, want warning", alert.Severity)
+	}
+
+	ordered := []Severity{SeverityDebug, SeverityInfo, SeverityWarning, SeverityError, SeverityCritical}
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i-1] >= ordered[i] {
+			t.Errorf("%s does not rank below %s", ordered[i-1], ordered[i])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// notify/alertlog.go - Appends alerts to a file as JSON Lines, as a durable audit trail.
+
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// alertLogFlushInterval is how often buffered alerts are written to the file
+const alertLogFlushInterval = time.Second
+
+// AlertLog appends every alert it receives to a file, one JSON object with
+// timestamp, message and severity per line. Writes are buffered, flushed
+// every second and when stopped.
+type AlertLog struct {
+	file        *os.File
+	writer      *bufio.Writer
+	encoder     *json.Encoder
+	closed      bool
+	mux         sync.Mutex // Guards the writer against the flush goroutine
+	stopChan    chan struct{}
+	debugMode   bool
+	debugLogger *log.Logger
+}
+
+// NewAlertLog creates a new AlertLog appending to the file at path,
+// creating it if needed
+func NewAlertLog(path string, debugMode bool) (*AlertLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &AlertLog{
+		file:      f,
+		writer:    bufio.NewWriter(f),
+		stopChan:  make(chan struct{}),
+		debugMode: debugMode,
+	}
+	l.encoder = json.NewEncoder(l.writer)
+
+	if debugMode {
+		df, err := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to create debug log file: %v", err)
+		}
+		l.debugLogger = log.New(df, "ALERTLOG: ", log.LstdFlags)
+	}
+
+	return l, nil
+}
+
+// Start begins flushing buffered alerts periodically
+func (l *AlertLog) Start() {
+	go l.flushAlerts()
+}
+
+// Stop flushes the remaining alerts and closes the file. Alerts handled
+// afterwards are dropped.
+func (l *AlertLog) Stop() {
+	close(l.stopChan)
+	if err := l.flush(true); err != nil && l.debugMode {
+		l.debugLogger.Printf("Failed to close alert log: %v", err)
+	}
+}
+
+// HandleStats implements Sink; stats are not logged
+func (l *AlertLog) HandleStats(stats *models.LogStats) {}
+
+// HandleAlert implements Sink by appending the alert to the buffer
+func (l *AlertLog) HandleAlert(alert models.Alert) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return
+	}
+	if err := l.encoder.Encode(alert); err != nil && l.debugMode {
+		l.debugLogger.Printf("Failed to write alert %q: %v", alert.Message, err)
+	}
+}
+
+// flushAlerts periodically flushes the buffer until stopped
+func (l *AlertLog) flushAlerts() {
+	ticker := time.NewTicker(alertLogFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stopChan:
+			return // Stop flushes and closes the file itself
+		case <-ticker.C:
+			if err := l.flush(false); err != nil && l.debugMode {
+				l.debugLogger.Printf("Failed to flush alert log: %v", err)
+			}
+		}
+	}
+}
+
+// flush writes out buffered alerts, closing the file when final is set
+func (l *AlertLog) flush(final bool) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	err := l.writer.Flush()
+	if final {
+		l.closed = true
+		if closeErr := l.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+// notify/sink.go - Delivers stats and alerts from the analyzer to every output.
+
+package notify
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"log_analyzer/models"
+)
+
+// Sink is an output for stats updates and alerts, e.g. the terminal display
+// or a webhook. Both methods are called from a single goroutine, so a sink
+// needs no locking of its own against the other, but must not block: a sink
+// that does slow work such as network delivery should queue it. The stats
+// are a clone the analyzer no longer touches, so they can be read, or kept
+// and read later, from any goroutine without locking; they are shared
+// between sinks and must not be modified.
+type Sink interface {
+	HandleStats(stats *models.LogStats)
+	HandleAlert(alert models.Alert)
+}
+
+// SinkFuncs adapts plain functions to a Sink, e.g. to receive stats in an
+// embedding program. Either function may be nil.
+type SinkFuncs struct {
+	Stats func(stats *models.LogStats)
+	Alert func(alert models.Alert)
+}
+
+// HandleStats implements Sink
+func (f SinkFuncs) HandleStats(stats *models.LogStats) {
+	if f.Stats != nil {
+		f.Stats(stats)
+	}
+}
+
+// HandleAlert implements Sink
+func (f SinkFuncs) HandleAlert(alert models.Alert) {
+	if f.Alert != nil {
+		f.Alert(alert)
+	}
+}
+
+// Dispatcher reads stats and alerts from the analyzer and hands each one to
+// every registered sink, in registration order
+type Dispatcher struct {
+	statsChan   chan *models.LogStats
+	alertChan   chan models.Alert
+	sinks       []Sink
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+	debugMode   bool
+	debugLogger *log.Logger
+}
+
+// NewDispatcher creates a new Dispatcher reading from statsChan and alertChan
+func NewDispatcher(statsChan chan *models.LogStats, alertChan chan models.Alert, debugMode bool) *Dispatcher {
+	d := &Dispatcher{
+		statsChan: statsChan,
+		alertChan: alertChan,
+		stopChan:  make(chan struct{}),
+		debugMode: debugMode,
+	}
+
+	if debugMode {
+		file, err := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to create debug log file: %v", err)
+		}
+		d.debugLogger = log.New(file, "DISPATCH: ", log.LstdFlags)
+	}
+
+	return d
+}
+
+// AddSink registers a sink that receives every stats update and alert.
+// It must be called before Start.
+func (d *Dispatcher) AddSink(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
+
+// Start begins dispatching
+func (d *Dispatcher) Start() {
+	if d.debugMode {
+		d.debugLogger.Printf("Dispatching to %d sink(s)", len(d.sinks))
+	}
+	d.wg.Add(1)
+	go d.dispatch()
+}
+
+// StartContext begins dispatching like Start, and stops the dispatcher
+// once ctx is cancelled
+func (d *Dispatcher) StartContext(ctx context.Context) {
+	d.Start()
+	go func() {
+		select {
+		case <-ctx.Done():
+			d.Stop()
+		case <-d.stopChan:
+		}
+	}()
+}
+
+// Stop signals the dispatcher to stop and waits until it has, so no sink
+// is called after Stop returns. It may be called more than once.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) dispatch() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.stopChan:
+			d.drainAlerts()
+			return
+		case stats := <-d.statsChan:
+			for _, sink := range d.sinks {
+				sink.HandleStats(stats)
+			}
+		case alert := <-d.alertChan:
+			for _, sink := range d.sinks {
+				sink.HandleAlert(alert)
+			}
+		}
+	}
+}
+
+// drainAlerts hands alerts still buffered at Stop to the sinks, e.g. ones
+// raised by the analyzer's final stats, so none is lost on shutdown
+func (d *Dispatcher) drainAlerts() {
+	for {
+		select {
+		case alert := <-d.alertChan:
+			for _, sink := range d.sinks {
+				sink.HandleAlert(alert)
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// notify/slack.go - Delivers alerts to a Slack incoming webhook, batching bursts.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// Slack posts alerts to a Slack incoming webhook. Alerts that fire within
+// batchInterval of each other are combined into a single message.
+type Slack struct {
+	webhook       *Webhook
+	batchInterval time.Duration
+}
+
+// slackMessage is the payload accepted by Slack incoming webhooks
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// NewSlack creates a new Slack notifier. A zero batchInterval sends one
+// message per alert.
+func NewSlack(url string, batchInterval time.Duration, debugMode bool) *Slack {
+	return &Slack{
+		webhook:       NewWebhook(url, debugMode),
+		batchInterval: batchInterval,
+	}
+}
+
+// Start begins delivering alerts
+func (s *Slack) Start() {
+	go s.deliverAlerts()
+}
+
+// Stop makes a single attempt at sending the alerts batched or still
+// queued and waits for it, up to webhookStopTimeout
+func (s *Slack) Stop() {
+	s.webhook.Stop()
+}
+
+// HandleStats implements Sink; stats are not delivered
+func (s *Slack) HandleStats(stats *models.LogStats) {}
+
+// HandleAlert implements Sink by queueing the alert for the next message
+func (s *Slack) HandleAlert(alert models.Alert) {
+	s.webhook.HandleAlert(alert)
+}
+
+func (s *Slack) deliverAlerts() {
+	defer close(s.webhook.doneChan)
+
+	var batch []models.Alert
+	var flush <-chan time.Time
+
+	for {
+		select {
+		case <-s.webhook.stopChan:
+			// Make a single attempt at delivering what is batched or queued
+			batch = append(batch, s.webhook.drain()...)
+			if len(batch) > 0 {
+				s.send(batch)
+			}
+			return
+		case alert := <-s.webhook.alertChan:
+			if s.batchInterval <= 0 {
+				s.send([]models.Alert{alert})
+				continue
+			}
+			if len(batch) == 0 {
+				flush = time.After(s.batchInterval)
+			}
+			batch = append(batch, alert)
+		case <-flush:
+			s.send(batch)
+			batch = nil
+			flush = nil
+		}
+	}
+}
+
+func (s *Slack) send(alerts []models.Alert) {
+	body, err := json.Marshal(slackMessage{Text: formatSlackText(alerts)})
+	if err != nil {
+		if s.webhook.debugMode {
+			s.webhook.debugLogger.Printf("Failed to encode Slack message: %v", err)
+		}
+		return
+	}
+
+	if err := s.webhook.post(body); err != nil && s.webhook.debugMode {
+		s.webhook.debugLogger.Printf("Dropped %d alert(s) for Slack: %v", len(alerts), err)
+	}
+}
+
+// formatSlackText renders alerts one per line, keeping the emoji the
+// analyzer already puts in each message
+func formatSlackText(alerts []models.Alert) string {
+	lines := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		lines = append(lines, fmt.Sprintf("[%s] %s", alert.Timestamp.Format("15:04:05"), alert.Message))
+	}
+	return strings.Join(lines, "\n")
+}
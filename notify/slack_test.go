@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackStopSendsBatchedAndQueuedAlerts(t *testing.T) {
+	endpoint := newTestEndpoint(t)
+
+	// The batch interval never expires, so only the stop sends
+	s := NewSlack(endpoint.URL, time.Hour, false)
+	s.Start()
+	for _, alert := range testAlerts(3) {
+		s.HandleAlert(alert)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the first alerts reach the batch
+	for _, alert := range testAlerts(2) {
+		s.HandleAlert(alert)
+	}
+	s.Stop()
+
+	posted := endpoint.posted()
+	if len(posted) != 1 {
+		t.Fatalf("%d messages sent by the time Stop returned, want 1", len(posted))
+	}
+	if got := strings.Count(posted[0], "High error rate"); got != 5 {
+		t.Errorf("message holds %d alerts, want 5: %s", got, posted[0])
+	}
+}
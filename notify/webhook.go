@@ -0,0 +1,182 @@
+// notify/webhook.go - Delivers alerts to an HTTP webhook as JSON.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"log_analyzer/models"
+)
+
+const (
+	webhookTimeout    = 5 * time.Second
+	webhookMaxRetries = 3
+	webhookRetryDelay = 500 * time.Millisecond
+	webhookQueueSize  = 100 // Alerts waiting for delivery before new ones are dropped
+
+	// How long Stop waits for the alerts still queued to be delivered
+	webhookStopTimeout = 10 * time.Second
+)
+
+// Webhook POSTs every alert it receives to a URL
+type Webhook struct {
+	url         string
+	alertChan   chan models.Alert
+	stopChan    chan struct{}
+	doneChan    chan struct{} // Closed once delivery has finished
+	client      *http.Client
+	debugMode   bool
+	debugLogger *log.Logger
+}
+
+// NewWebhook creates a new Webhook delivering alerts to url
+func NewWebhook(url string, debugMode bool) *Webhook {
+	w := &Webhook{
+		url:       url,
+		alertChan: make(chan models.Alert, webhookQueueSize),
+		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		client:    &http.Client{Timeout: webhookTimeout},
+		debugMode: debugMode,
+	}
+
+	if debugMode {
+		f, err := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to create debug log file: %v", err)
+		}
+		w.debugLogger = log.New(f, "WEBHOOK: ", log.LstdFlags)
+	}
+
+	return w
+}
+
+// Start begins delivering alerts
+func (w *Webhook) Start() {
+	go w.deliverAlerts()
+}
+
+// Stop makes a single attempt at delivering each alert still queued and
+// waits for it, up to webhookStopTimeout
+func (w *Webhook) Stop() {
+	close(w.stopChan)
+	w.waitDone()
+}
+
+// waitDone waits for delivery to finish after a stop, giving up after
+// webhookStopTimeout
+func (w *Webhook) waitDone() {
+	select {
+	case <-w.doneChan:
+	case <-time.After(webhookStopTimeout):
+		if w.debugMode {
+			w.debugLogger.Printf("Gave up waiting for alert delivery after %s", webhookStopTimeout)
+		}
+	}
+}
+
+// HandleStats implements Sink; stats are not delivered
+func (w *Webhook) HandleStats(stats *models.LogStats) {}
+
+// HandleAlert implements Sink by queueing the alert for delivery. It never
+// blocks, so a slow endpoint cannot stall the other sinks; the alert is
+// dropped when the queue is full.
+func (w *Webhook) HandleAlert(alert models.Alert) {
+	select {
+	case w.alertChan <- alert:
+	default:
+		if w.debugMode {
+			w.debugLogger.Printf("Dropped alert, delivery queue full: %s", alert.Message)
+		}
+	}
+}
+
+func (w *Webhook) deliverAlerts() {
+	defer close(w.doneChan)
+
+	for {
+		select {
+		case <-w.stopChan:
+			// Alerts may still be queued, whichever case was picked first
+			deadline := time.Now().Add(webhookStopTimeout)
+			for _, alert := range w.drain() {
+				if time.Now().After(deadline) {
+					break
+				}
+				w.deliver(alert)
+			}
+			return
+		case alert := <-w.alertChan:
+			w.deliver(alert)
+		}
+	}
+}
+
+// drain empties the queue without blocking, returning the alerts in it
+func (w *Webhook) drain() []models.Alert {
+	var alerts []models.Alert
+	for {
+		select {
+		case alert := <-w.alertChan:
+			alerts = append(alerts, alert)
+		default:
+			return alerts
+		}
+	}
+}
+
+// deliver posts a single alert, logging it when dropped
+func (w *Webhook) deliver(alert models.Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		if w.debugMode {
+			w.debugLogger.Printf("Failed to encode alert: %v", err)
+		}
+		return
+	}
+	if err := w.post(body); err != nil && w.debugMode {
+		w.debugLogger.Printf("Dropped alert %q: %v", alert.Message, err)
+	}
+}
+
+// post sends body to the webhook, retrying a bounded number of times
+func (w *Webhook) post(body []byte) error {
+	var lastErr error
+	for attempt := 1; attempt <= webhookMaxRetries; attempt++ {
+		lastErr = w.postOnce(body)
+		if lastErr == nil {
+			return nil
+		}
+		if w.debugMode {
+			w.debugLogger.Printf("Delivery attempt %d failed: %v", attempt, lastErr)
+		}
+
+		if attempt < webhookMaxRetries {
+			select {
+			case <-w.stopChan:
+				return lastErr
+			case <-time.After(webhookRetryDelay * time.Duration(attempt)):
+			}
+		}
+	}
+	return lastErr
+}
+
+func (w *Webhook) postOnce(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
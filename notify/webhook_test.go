@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// testEndpoint records the bodies posted to it
+type testEndpoint struct {
+	*httptest.Server
+
+	mux    sync.Mutex
+	bodies []string
+}
+
+func newTestEndpoint(t *testing.T) *testEndpoint {
+	t.Helper()
+
+	e := &testEndpoint{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		e.mux.Lock()
+		e.bodies = append(e.bodies, string(body))
+		e.mux.Unlock()
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// posted returns the bodies received so far
+func (e *testEndpoint) posted() []string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return append([]string(nil), e.bodies...)
+}
+
+func testAlerts(n int) []models.Alert {
+	alerts := make([]models.Alert, n)
+	for i := range alerts {
+		alerts[i] = models.Alert{
+			Timestamp: time.Date(2024, 3, 1, 12, 0, i, 0, time.UTC),
+			Message:   "High error rate",
+		}
+	}
+	return alerts
+}
+
+func TestWebhookStopDeliversQueuedAlerts(t *testing.T) {
+	endpoint := newTestEndpoint(t)
+
+	// Queued before delivery starts, so the stop races them
+	w := NewWebhook(endpoint.URL, false)
+	for _, alert := range testAlerts(5) {
+		w.HandleAlert(alert)
+	}
+	w.Start()
+	w.Stop()
+
+	if got := len(endpoint.posted()); got != 5 {
+		t.Errorf("%d alerts delivered by the time Stop returned, want 5", got)
+	}
+}
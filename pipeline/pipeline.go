@@ -0,0 +1,565 @@
+// pipeline/pipeline.go - Wires the reader, analyzer and outputs together, for the CLI or embedding.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"slices"
+	"time"
+
+	"log_analyzer/analyzer"
+	"log_analyzer/display"
+	"log_analyzer/models"
+	"log_analyzer/notify"
+	"log_analyzer/queue"
+	"log_analyzer/reader"
+	"log_analyzer/server"
+)
+
+const (
+	StatsChannelSize = 10
+	AlertChannelSize = 100
+
+	// DrainTimeout bounds how long shutdown waits for buffered entries
+	DrainTimeout = 2 * time.Second
+
+	// EOFDrainTimeout is the drain bound with StopAtEOF, long enough for a
+	// full log channel, since every entry of a finite input should count
+	EOFDrainTimeout = time.Minute
+)
+
+// Options configures a Pipeline. Start from DefaultOptions, which matches
+// the command-line defaults; the zero value of some fields disables a
+// feature rather than selecting its default.
+type Options struct {
+	// Input
+	Paths         []string  // Log files read in order as one stream; Input is read when empty
+	Input         io.Reader // Read when Paths is empty, defaults to stdin
+	Listen        string    // Receive lines from this udp:// or tcp:// address instead, see reader.NewListenReader
+	Follow        bool      // Keep reading the last file as it grows
+	StopAtEOF     bool      // Shut down once Input ends too (files always end unless followed)
+	Framing       reader.Framing
+	Decoder       reader.LineDecoder // Optional decoder for each record, see reader.SetDecoder
+	Parser        reader.Parser      // Optional parser replacing the built-in parsing, see reader.SetParser
+	ParserWorkers int
+	MaxLineSize   int          // Longer lines are skipped as invalid
+	Overflow      queue.Policy // What happens to entries when the analyzer's queue is full
+	RejectFile    string
+	Replay        bool
+	ReplaySpeed   float64
+
+	// Parsing and filtering
+	Format          *regexp.Regexp      // Custom line format with named groups
+	Syslog          reader.SyslogFormat // Parse lines as syslog when set, instead of with Format
+	JSON            bool                // Parse each line as a JSON object
+	Multiline       bool                // Join continuation lines, e.g. stack traces, onto the entry before them
+	Continuation    *regexp.Regexp      // Lines matching it are continuations with Multiline; nil uses Format
+	JSONFields      reader.JSONFields
+	TimeLayouts     []string
+	Timezone        *time.Location // Zone of timestamps without an offset; nil reads them as UTC
+	DisplayTimezone bool           // Show report times in Timezone rather than UTC
+	Levels          []string
+	ErrorPatterns   []*regexp.Regexp
+	Fingerprint     []reader.FingerprintRule
+	DurationPattern *regexp.Regexp
+	MinLevel        string
+	Include         *regexp.Regexp
+	Exclude         *regexp.Regexp
+	Since           time.Time // Entries logged before it are filtered, zero leaves the range open
+	Until           time.Time // Entries logged at or after it are filtered, zero leaves the range open
+
+	// Analysis
+	BufferSize          int
+	BufferGrowAt        float64
+	BufferGrowFactor    float64
+	BufferShrinkBelow   float64
+	IPErrorShare        float64
+	ErrorAlertThreshold float64
+	RateHigh            float64
+	RateLow             float64
+	RateAlpha           float64
+	RateBucket          time.Duration // Interval entries are counted in for rates
+	SmoothWindow        bool
+	ResizeTicks         int
+	Patterns            analyzer.PatternConfig
+	AlertCooldown       time.Duration
+	AnomalySigma        float64
+	StaleAfter          time.Duration // Silence of the input that raises an alert, 0 disables
+	StatsRotate         time.Duration // Interval the cumulative stats are reset at, 0 never resets
+	SkewThreshold       time.Duration // Median skew of log timestamps from the wall clock that raises an alert, 0 disables
+	LatencyAlert        time.Duration // p99 latency that raises an alert, 0 disables
+	LatencyAlertTicks   int           // Consecutive seconds p99 latency must stay above LatencyAlert
+	ParseAlert          float64       // Parse success rate (%) over ParseAlertWindow that raises an alert, 0 disables
+	ParseAlertWindow    time.Duration // Period the parse success rate is measured over
+	SLO                 float64       // Success-rate objective in percent, 0 disables
+	FilteredInRate      bool
+	CompactWindow       bool
+	GroupBy             string // Dimension whose most frequent values are reported: level, ip, error_type or a field of the line format
+	TimeReference       analyzer.TimeReference
+	RateCSV             string
+	StateFile           string        // Checkpoint of the cumulative stats, written every StateInterval and on shutdown
+	StateInterval       time.Duration // 0 only writes StateFile on shutdown
+	Resume              bool          // Continue the cumulative stats from StateFile, if it exists
+
+	// Outputs
+	Display      bool // Render stats and alerts on stdout
+	Output       display.OutputMode
+	Color        display.ColorMode
+	NoClear      bool
+	Refresh      time.Duration // Minimum interval between renders, 0 renders every update
+	Once         bool
+	TopN         int
+	AlertHistory int           // Alerts kept by the display
+	AlertShow    int           // Most recent alerts shown
+	AlertMaxAge  time.Duration // Alerts older than this are dropped from the display, 0 keeps them
+	HTTPAddr     string
+	ProfileAddr  string // Serve net/http/pprof profiles at this address
+	AlertLog     string // Append alerts to this file as JSON Lines
+	AlertWebhook string
+	SlackWebhook string
+	SlackBatch   time.Duration
+	Sinks        []notify.Sink // Additional outputs, e.g. notify.SinkFuncs
+
+	Debug      bool
+	OnShutdown func() // Called once shutdown begins, before draining
+}
+
+// DefaultOptions returns the options used by the command line when no
+// flags are given
+func DefaultOptions() Options {
+	return Options{
+		Framing:             reader.FramingNewline,
+		ParserWorkers:       1,
+		MaxLineSize:         reader.DefaultMaxLineSize,
+		ReplaySpeed:         1,
+		JSONFields:          reader.DefaultJSONFields(),
+		Levels:              reader.DefaultLevels,
+		BufferSize:          10000,
+		Overflow:            queue.PolicyBlock,
+		BufferGrowAt:        analyzer.DefaultBufferGrowAt,
+		BufferGrowFactor:    analyzer.DefaultBufferGrowFactor,
+		BufferShrinkBelow:   analyzer.DefaultBufferShrinkBelow,
+		IPErrorShare:        0.5,
+		ErrorAlertThreshold: analyzer.DefaultErrorAlertThreshold,
+		RateHigh:            analyzer.DefaultRateHigh,
+		RateLow:             analyzer.DefaultRateLow,
+		RateAlpha:           analyzer.DefaultRateAlpha,
+		RateBucket:          analyzer.DefaultRateBucket,
+		ResizeTicks:         analyzer.DefaultResizeTicks,
+		Patterns:            analyzer.DefaultPatternConfig(),
+		AlertCooldown:       analyzer.DefaultAlertCooldown,
+		AnomalySigma:        analyzer.DefaultAnomalySigma,
+		StaleAfter:          analyzer.DefaultStaleAfter,
+		SkewThreshold:       analyzer.DefaultSkewThreshold,
+		LatencyAlertTicks:   analyzer.DefaultLatencyAlertTicks,
+		StateInterval:       analyzer.DefaultStateInterval,
+		ParseAlert:          analyzer.DefaultParseAlertThreshold,
+		ParseAlertWindow:    analyzer.DefaultParseAlertWindow,
+		FilteredInRate:      true,
+		TimeReference:       analyzer.TimeWall,
+		Display:             true,
+		Output:              display.OutputText,
+		Color:               display.ColorAuto,
+		TopN:                display.DefaultTopN,
+		AlertHistory:        display.DefaultAlertHistory,
+		AlertShow:           display.DefaultAlertShow,
+		SlackBatch:          5 * time.Second,
+	}
+}
+
+// Pipeline reads log entries, analyzes them and delivers stats and alerts
+// to its outputs
+type Pipeline struct {
+	opts       Options
+	reader     *reader.Reader
+	analyzer   *analyzer.Analyzer
+	display    *display.Display
+	dispatcher *notify.Dispatcher
+	alertLog   *notify.AlertLog
+	webhook    *notify.Webhook
+	slack      *notify.Slack
+	server     *server.Server
+	profiler   *server.Profiler
+	ran        bool
+}
+
+// New creates a Pipeline, validating the options and opening its input
+func New(opts Options) (*Pipeline, error) {
+	// The default levels lack some syslog severities, see reader.SetSyslogMode
+	if opts.Syslog != "" && slices.Equal(opts.Levels, reader.DefaultLevels) {
+		opts.Levels = reader.SyslogLevels
+	}
+	p := &Pipeline{opts: opts}
+
+	if _, err := queue.ParsePolicy(string(opts.Overflow)); err != nil {
+		return nil, fmt.Errorf("Overflow: %w", err)
+	}
+	entries := queue.New(opts.BufferSize, opts.Overflow)
+	statsChan := make(chan *models.LogStats, StatsChannelSize)
+	alertChan := make(chan models.Alert, AlertChannelSize)
+
+	if err := p.newReader(entries); err != nil {
+		return nil, err
+	}
+	if err := p.newAnalyzer(entries, statsChan, alertChan); err != nil {
+		return nil, err
+	}
+
+	// Hand stats and alerts to the display and every other configured sink
+	p.dispatcher = notify.NewDispatcher(statsChan, alertChan, opts.Debug)
+	if opts.Display {
+		p.display = display.NewDisplay()
+		p.display.SetLevels(opts.Levels)
+		p.display.SetOutput(opts.Output)
+		if err := p.display.SetTopN(opts.TopN); err != nil {
+			return nil, fmt.Errorf("TopN: %w", err)
+		}
+		if err := p.display.SetAlertRetention(opts.AlertHistory, opts.AlertShow, opts.AlertMaxAge); err != nil {
+			return nil, fmt.Errorf("alert retention: %w", err)
+		}
+		p.display.SetNoClear(opts.NoClear)
+		if opts.DisplayTimezone && opts.Timezone != nil {
+			p.display.SetLocation(opts.Timezone)
+		}
+		if err := p.display.SetRefresh(opts.Refresh); err != nil {
+			return nil, fmt.Errorf("Refresh: %w", err)
+		}
+		p.display.SetColor(opts.Color)
+		p.display.SetOnce(opts.Once)
+		p.dispatcher.AddSink(p.display)
+	}
+	if opts.AlertLog != "" {
+		alertLog, err := notify.NewAlertLog(opts.AlertLog, opts.Debug)
+		if err != nil {
+			return nil, fmt.Errorf("open alert log: %w", err)
+		}
+		p.alertLog = alertLog
+		p.dispatcher.AddSink(p.alertLog)
+	}
+	if opts.AlertWebhook != "" {
+		p.webhook = notify.NewWebhook(opts.AlertWebhook, opts.Debug)
+		p.dispatcher.AddSink(p.webhook)
+	}
+	if opts.SlackWebhook != "" {
+		p.slack = notify.NewSlack(opts.SlackWebhook, opts.SlackBatch, opts.Debug)
+		p.dispatcher.AddSink(p.slack)
+	}
+	for _, sink := range opts.Sinks {
+		p.dispatcher.AddSink(sink)
+	}
+
+	if opts.HTTPAddr != "" {
+		p.server = server.NewServer(opts.HTTPAddr, p.analyzer)
+		p.dispatcher.AddSink(p.server)
+	}
+	if opts.ProfileAddr != "" {
+		p.profiler = server.NewProfiler(opts.ProfileAddr)
+	}
+
+	return p, nil
+}
+
+func (p *Pipeline) newReader(entries *queue.Queue) error {
+	opts := p.opts
+
+	// Read the given log files in order, falling back to Input or stdin
+	var err error
+	switch {
+	case opts.Listen != "":
+		if len(opts.Paths) > 0 || opts.Input != nil {
+			return errors.New("Listen: network input cannot be combined with Paths or Input")
+		}
+		if opts.Multiline {
+			return errors.New("Multiline: lines from different network clients interleave, so they cannot be joined")
+		}
+		p.reader, err = reader.NewListenReader(opts.Listen, entries, opts.Debug)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	case len(opts.Paths) > 0:
+		p.reader, err = reader.NewFilesReader(opts.Paths, entries, opts.Debug)
+		if err != nil {
+			return fmt.Errorf("open log file: %w", err)
+		}
+		p.reader.SetFollow(opts.Follow)
+	case opts.Input != nil:
+		p.reader = reader.NewStreamReader(opts.Input, entries, opts.Debug)
+	default:
+		p.reader = reader.NewReader(entries, opts.Debug)
+	}
+
+	r := p.reader
+	r.SetTimeLayouts(opts.TimeLayouts)
+	r.SetLocation(opts.Timezone)
+	r.SetLevels(opts.Levels)
+	r.SetErrorPatterns(opts.ErrorPatterns)
+	r.SetFingerprintRules(opts.Fingerprint)
+	r.SetParserWorkers(opts.ParserWorkers)
+	if opts.MinLevel != "" {
+		if err := r.SetMinLevel(opts.MinLevel); err != nil {
+			return fmt.Errorf("MinLevel: %w", err)
+		}
+	}
+	r.SetLineFilters(opts.Include, opts.Exclude)
+	r.SetTimeRange(opts.Since, opts.Until)
+	r.SetFraming(opts.Framing)
+	if err := r.SetMaxLineSize(opts.MaxLineSize); err != nil {
+		return fmt.Errorf("MaxLineSize: %w", err)
+	}
+	if opts.RejectFile != "" {
+		if err := r.SetRejectFile(opts.RejectFile); err != nil {
+			return fmt.Errorf("open reject file: %w", err)
+		}
+	}
+	if opts.Replay {
+		if err := r.SetReplay(opts.ReplaySpeed); err != nil {
+			return fmt.Errorf("ReplaySpeed: %w", err)
+		}
+	}
+	if opts.DurationPattern != nil {
+		if err := r.SetDurationPattern(opts.DurationPattern); err != nil {
+			return fmt.Errorf("DurationPattern: %w", err)
+		}
+	}
+	if opts.Multiline {
+		if opts.JSON || opts.Decoder != nil || opts.Parser != nil {
+			return errors.New("Multiline: only text lines can be joined, not JSON, decoded records or custom parsers")
+		}
+		r.SetMultiline(opts.Continuation)
+	}
+	if opts.JSON {
+		r.SetJSONMode(opts.JSONFields)
+	} else if opts.Syslog != "" {
+		r.SetSyslogMode(opts.Syslog)
+	} else if opts.Format != nil {
+		if err := r.SetPattern(opts.Format, reader.FieldMapFromNames(opts.Format)); err != nil {
+			return fmt.Errorf("Format: %w", err)
+		}
+	}
+
+	if opts.GroupBy != "" && opts.Decoder == nil && opts.Parser == nil {
+		if err := checkGroupBy(opts); err != nil {
+			return fmt.Errorf("GroupBy: %w", err)
+		}
+	}
+
+	// A decoder or custom parser takes over from the modes above
+	switch {
+	case opts.Decoder != nil && opts.Parser != nil:
+		return errors.New("Parser: cannot be combined with Decoder")
+	case opts.Decoder != nil:
+		r.SetDecoder(opts.Decoder)
+	case opts.Parser != nil:
+		r.SetParser(opts.Parser)
+	}
+	return nil
+}
+
+// checkGroupBy makes sure the line format extracts a group-by field. The
+// built-in dimensions need no format, and a custom parser or decoder sets
+// entry fields itself, so neither is checked.
+func checkGroupBy(opts Options) error {
+	switch opts.GroupBy {
+	case analyzer.GroupLevel, analyzer.GroupIP, analyzer.GroupErrorType,
+		reader.FieldTimestamp, reader.FieldMessage: // Refused by the analyzer
+		return nil
+	}
+	if opts.JSON || opts.Syslog != "" || opts.Format == nil || opts.Format.SubexpIndex(opts.GroupBy) < 0 {
+		return fmt.Errorf("needs a Format with a (?P<%s>...) group", opts.GroupBy)
+	}
+	return nil
+}
+
+func (p *Pipeline) newAnalyzer(entries *queue.Queue, statsChan chan *models.LogStats, alertChan chan models.Alert) error {
+	opts := p.opts
+
+	a := analyzer.NewAnalyzer(entries, statsChan, alertChan, opts.Debug)
+	p.analyzer = a
+	if err := a.SetRateBucket(opts.RateBucket); err != nil {
+		return fmt.Errorf("RateBucket: %w", err)
+	}
+	a.SetIPErrorShareThreshold(opts.IPErrorShare)
+	a.SetCompactWindow(opts.CompactWindow)
+	if err := a.SetGroupBy(opts.GroupBy); err != nil {
+		return fmt.Errorf("GroupBy: %w", err)
+	}
+	a.SetTimeReference(opts.TimeReference)
+	a.SetAlertCooldown(opts.AlertCooldown)
+	a.SetFilteredInRate(opts.FilteredInRate)
+	if opts.RateCSV != "" {
+		if err := a.SetRateCSV(opts.RateCSV); err != nil {
+			return fmt.Errorf("open rate CSV: %w", err)
+		}
+	}
+	if err := a.SetThresholds(opts.ErrorAlertThreshold, opts.RateHigh, opts.RateLow); err != nil {
+		return fmt.Errorf("thresholds: %w", err)
+	}
+	if err := a.SetRateSmoothing(opts.RateAlpha, opts.SmoothWindow); err != nil {
+		return fmt.Errorf("RateAlpha: %w", err)
+	}
+	if err := a.SetPatternConfig(opts.Patterns); err != nil {
+		return fmt.Errorf("Patterns: %w", err)
+	}
+	if err := a.SetResizeTicks(opts.ResizeTicks); err != nil {
+		return fmt.Errorf("ResizeTicks: %w", err)
+	}
+	if err := a.SetBufferGrowth(opts.BufferGrowAt, opts.BufferGrowFactor); err != nil {
+		return fmt.Errorf("buffer growth: %w", err)
+	}
+	if err := a.SetBufferShrink(opts.BufferShrinkBelow); err != nil {
+		return fmt.Errorf("BufferShrinkBelow: %w", err)
+	}
+	if err := a.SetAnomalySigma(opts.AnomalySigma); err != nil {
+		return fmt.Errorf("AnomalySigma: %w", err)
+	}
+	if err := a.SetStaleAfter(opts.StaleAfter); err != nil {
+		return fmt.Errorf("StaleAfter: %w", err)
+	}
+	if err := a.SetSkewThreshold(opts.SkewThreshold); err != nil {
+		return fmt.Errorf("SkewThreshold: %w", err)
+	}
+	if err := a.SetLatencyAlert(opts.LatencyAlert, opts.LatencyAlertTicks); err != nil {
+		return fmt.Errorf("LatencyAlert: %w", err)
+	}
+	if err := a.SetParseAlert(opts.ParseAlert, opts.ParseAlertWindow); err != nil {
+		return fmt.Errorf("ParseAlert: %w", err)
+	}
+	if err := a.SetStatsRotation(opts.StatsRotate); err != nil {
+		return fmt.Errorf("StatsRotate: %w", err)
+	}
+	if err := a.SetSLO(opts.SLO); err != nil {
+		return fmt.Errorf("SLO: %w", err)
+	}
+	if opts.StateFile != "" {
+		if err := a.SetStateFile(opts.StateFile, opts.StateInterval); err != nil {
+			return fmt.Errorf("StateFile: %w", err)
+		}
+	}
+	if opts.Resume {
+		return resumeState(a, opts.StateFile)
+	}
+	return nil
+}
+
+// resumeState continues the analyzer's cumulative stats from the state
+// file. A missing file is a first run, which starts from zero.
+func resumeState(a *analyzer.Analyzer, path string) error {
+	if path == "" {
+		return errors.New("Resume: needs a StateFile")
+	}
+
+	state, err := analyzer.LoadState(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Resume: %w", err)
+	}
+	if err := a.RestoreState(state); err != nil {
+		return fmt.Errorf("Resume: %w", err)
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the most recently generated stats
+func (p *Pipeline) Snapshot() *models.LogStats {
+	return p.analyzer.Snapshot()
+}
+
+// WindowSnapshot returns a copy of the analyzer's sliding window contents
+func (p *Pipeline) WindowSnapshot() analyzer.WindowSnapshot {
+	return p.analyzer.WindowSnapshot()
+}
+
+// Run starts the pipeline and blocks until ctx is cancelled or, for file
+// input or with StopAtEOF, the input ends. It then shuts down gracefully,
+// draining buffered entries, and returns the final stats, which the
+// display has rendered once more. A Pipeline can only be run once.
+func (p *Pipeline) Run(ctx context.Context) (*models.LogStats, error) {
+	if p.ran {
+		return nil, errors.New("pipeline has already been run")
+	}
+	p.ran = true
+
+	// Bind the HTTP servers first, so a bad address fails before any work
+	if p.server != nil {
+		if err := p.server.Start(); err != nil {
+			return nil, fmt.Errorf("start HTTP server: %w", err)
+		}
+	}
+	if p.profiler != nil {
+		if err := p.profiler.Start(); err != nil {
+			if p.server != nil {
+				p.server.Stop()
+			}
+			return nil, fmt.Errorf("start profiler: %w", err)
+		}
+	}
+
+	p.reader.StartContext(ctx)
+	p.analyzer.StartContext(ctx)
+	// The dispatcher is stopped explicitly after the analyzer has drained,
+	// so alerts raised while draining still reach the sinks
+	p.dispatcher.Start()
+	if p.alertLog != nil {
+		p.alertLog.Start()
+	}
+	if p.webhook != nil {
+		p.webhook.Start()
+	}
+	if p.slack != nil {
+		p.slack.Start()
+	}
+
+	// File input shuts down once the last file has been fully read (unless
+	// followed); other input runs until cancelled, unless StopAtEOF is set
+	var inputDone <-chan struct{}
+	if (len(p.opts.Paths) > 0 && !p.opts.Follow) || p.opts.StopAtEOF {
+		inputDone = p.reader.Done()
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-inputDone:
+	}
+	if p.opts.OnShutdown != nil {
+		p.opts.OnShutdown()
+	}
+
+	// Stop the reader first so the analyzer can drain what is already
+	// buffered and publish final stats, then stop the outputs. Components
+	// watching a cancelled context may have stopped already; stopping
+	// again is harmless.
+	p.reader.Stop()
+	drainTimeout := DrainTimeout
+	if p.opts.StopAtEOF {
+		drainTimeout = EOFDrainTimeout
+	}
+	finalStats := p.analyzer.StopAndDrain(drainTimeout)
+	p.dispatcher.Stop()
+	if p.server != nil {
+		p.server.Stop()
+	}
+	if p.profiler != nil {
+		p.profiler.Stop()
+	}
+	if p.slack != nil {
+		p.slack.Stop()
+	}
+	if p.webhook != nil {
+		p.webhook.Stop()
+	}
+	if p.alertLog != nil {
+		p.alertLog.Stop()
+	}
+	if p.display != nil {
+		p.display.RenderFinal(finalStats)
+	}
+
+	return finalStats, nil
+}
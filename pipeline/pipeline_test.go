@@ -0,0 +1,194 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"log_analyzer/display"
+	"log_analyzer/models"
+	"log_analyzer/notify"
+)
+
+// captureStdout sends what the display prints to a temporary file until the
+// returned function is called, which restores stdout and returns the file
+func captureStdout(t *testing.T) func() *os.File {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = f
+	return func() *os.File {
+		os.Stdout = stdout
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+}
+
+func TestPipelineUnderLoad(t *testing.T) {
+	const (
+		batches  = 150
+		perBatch = 100
+		lines    = batches * perBatch
+	)
+
+	// Every 10th line is malformed, every 4th valid one an ERROR
+	valid, errorLines := 0, 0
+	input, output := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(output)
+		for i := 0; i < lines; i++ {
+			now := time.Now().UTC().Format(time.RFC3339Nano)
+			switch {
+			case i%10 == 9:
+				fmt.Fprintf(w, "garbage line %d\n", i)
+			case i%4 == 0:
+				fmt.Fprintf(w, "[%s] ERROR - IP:10.0.%d.%d Error 500 - Timeout\n", now, i%3, i%200)
+			default:
+				fmt.Fprintf(w, "[%s] INFO - IP:10.0.%d.%d GET /orders/%d\n", now, i%3, i%200, i)
+			}
+			if i%perBatch == perBatch-1 {
+				w.Flush()
+				time.Sleep(10 * time.Millisecond) // Spread the input over several stats ticks
+			}
+		}
+		w.Flush()
+		output.Close()
+	}()
+	for i := 0; i < lines; i++ {
+		if i%10 != 9 {
+			valid++
+			if i%4 == 0 {
+				errorLines++
+			}
+		}
+	}
+
+	// Sinks see every published snapshot while the pipeline keeps running
+	var sinkMux sync.Mutex
+	published := 0
+	lastProcessed := 0
+	sink := notify.SinkFuncs{
+		Stats: func(stats *models.LogStats) {
+			sinkMux.Lock()
+			defer sinkMux.Unlock()
+			published++
+			if stats.EntriesProcessed < lastProcessed {
+				t.Errorf("entries processed went back from %d to %d", lastProcessed, stats.EntriesProcessed)
+			}
+			lastProcessed = stats.EntriesProcessed
+		},
+	}
+
+	opts := DefaultOptions()
+	opts.Input = input
+	opts.StopAtEOF = true
+	opts.ParserWorkers = 4
+	opts.BufferSize = 64 // Small, so the queue fills and grows
+	opts.Output = display.OutputJSON
+	opts.Color = display.ColorNever
+	opts.NoClear = true
+	opts.Sinks = []notify.Sink{sink}
+
+	restore := captureStdout(t)
+	p, err := New(opts)
+	if err != nil {
+		restore()
+		t.Fatal(err)
+	}
+
+	// Read the published stats concurrently, as the HTTP server does
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var readers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for ctx.Err() == nil {
+				if stats := p.Snapshot(); stats != nil {
+					_ = stats.LevelCounts["ERROR"]
+				}
+				p.WindowSnapshot()
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+
+	final, err := p.Run(context.Background())
+	cancel()
+	readers.Wait()
+	out := restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if final.EntriesProcessed != valid {
+		t.Errorf("EntriesProcessed = %d, want %d", final.EntriesProcessed, valid)
+	}
+	if final.TotalLines != lines {
+		t.Errorf("TotalLines = %d, want %d", final.TotalLines, lines)
+	}
+	if final.SkippedEntries != lines-valid {
+		t.Errorf("SkippedEntries = %d, want %d", final.SkippedEntries, lines-valid)
+	}
+	if got := final.Lifetime.LevelCounts["ERROR"]; got != errorLines {
+		t.Errorf("lifetime ERROR count = %d, want %d", got, errorLines)
+	}
+	if got := final.Lifetime.ErrorCounts["Timeout"]; got != errorLines {
+		t.Errorf("lifetime Timeout count = %d, want %d", got, errorLines)
+	}
+
+	sinkMux.Lock()
+	if published == 0 {
+		t.Error("no stats were published to the sinks")
+	}
+	sinkMux.Unlock()
+
+	// The display rendered the final stats last
+	type report struct {
+		Type             string `json:"type"`
+		EntriesProcessed int    `json:"entries_processed"`
+	}
+	var last report
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	rendered := 0
+	for scanner.Scan() {
+		var line report
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("display printed invalid JSON %q: %v", scanner.Text(), err)
+		}
+		if line.Type == "stats" {
+			last = line
+			rendered++
+		}
+	}
+	if rendered == 0 || last.EntriesProcessed != valid {
+		t.Errorf("display rendered %d stats, the last with %d entries, want %d", rendered, last.EntriesProcessed, valid)
+	}
+}
+
+func TestNewRefusesUnknownMinLevel(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Input = strings.NewReader("")
+	opts.Display = false
+	opts.MinLevel = "WARNN"
+
+	if _, err := New(opts); err == nil || !strings.Contains(err.Error(), "MinLevel") {
+		t.Errorf("New with MinLevel WARNN returned %v, want a MinLevel error", err)
+	}
+}
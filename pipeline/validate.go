@@ -0,0 +1,90 @@
+// pipeline/validate.go - Dry run checking that the parser settings match a sample of the input.
+
+package pipeline
+
+import (
+	"log_analyzer/models"
+	"log_analyzer/queue"
+)
+
+// validateExamples is how many parsed entries and rejected lines a
+// ValidationReport keeps as examples
+const validateExamples = 3
+
+// ValidationReport summarizes how well the parser settings match the first
+// lines of the input
+type ValidationReport struct {
+	Lines    int               // Lines read
+	Parsed   int               // Lines that parsed, including filtered ones
+	Rejects  map[string]int    // Lines that failed to parse, by reject reason
+	Examples []models.LogEntry // The first few parsed entries that were not filtered
+	Rejected []models.LogEntry // The first rejected line of each of the first few reasons
+}
+
+// SuccessRate returns the percentage of lines that parsed, or 100 if no
+// lines were read
+func (r *ValidationReport) SuccessRate() float64 {
+	if r.Lines == 0 {
+		return 100
+	}
+	return 100 * float64(r.Parsed) / float64(r.Lines)
+}
+
+// Validate parses up to lines lines of the input configured in opts with
+// its parser settings, without analyzing them, and reports how many parsed.
+// Following, replay pacing and dropping are turned off, so the sample is
+// read as fast as possible and ends with the input. Network input and
+// named pipes are read until enough lines have arrived.
+func Validate(opts Options, lines int) (*ValidationReport, error) {
+	opts.Follow = false
+	opts.Replay = false
+	opts.Overflow = queue.PolicyBlock
+
+	p := &Pipeline{opts: opts}
+	entries := queue.New(opts.BufferSize, opts.Overflow)
+	if err := p.newReader(entries); err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Rejects: make(map[string]int)}
+	p.reader.Start()
+	defer p.reader.Stop()
+
+	for report.Lines < lines {
+		if entry, ok := entries.TryPop(); ok {
+			report.add(entry)
+			continue
+		}
+		select {
+		case <-entries.Ready():
+		case <-p.reader.Done():
+			// Take what was forwarded before the input ended
+			for report.Lines < lines {
+				entry, ok := entries.TryPop()
+				if !ok {
+					break
+				}
+				report.add(entry)
+			}
+			return report, nil
+		}
+	}
+	return report, nil
+}
+
+// add counts one entry forwarded by the reader
+func (r *ValidationReport) add(entry models.LogEntry) {
+	r.Lines++
+	if entry.IsValid {
+		r.Parsed++
+		if !entry.Filtered && len(r.Examples) < validateExamples {
+			r.Examples = append(r.Examples, entry)
+		}
+		return
+	}
+
+	if r.Rejects[entry.Reject] == 0 && len(r.Rejected) < validateExamples {
+		r.Rejected = append(r.Rejected, entry)
+	}
+	r.Rejects[entry.Reject]++
+}
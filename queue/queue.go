@@ -0,0 +1,180 @@
+// queue/queue.go - Bounded ring buffer of log entries between the reader and the analyzer.
+
+package queue
+
+import (
+	"fmt"
+	"sync"
+
+	"log_analyzer/models"
+)
+
+// Policy selects what Push does when the queue is full
+type Policy string
+
+const (
+	// PolicyBlock makes Push wait for room, slowing the reader down
+	PolicyBlock Policy = "block"
+	// PolicyDropOldest makes room by dropping the entry waiting longest
+	PolicyDropOldest Policy = "drop-oldest"
+	// PolicyDropNewest drops the entry being pushed
+	PolicyDropNewest Policy = "drop-newest"
+)
+
+// ParsePolicy validates an overflow policy name
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case PolicyBlock, PolicyDropOldest, PolicyDropNewest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q", name)
+	}
+}
+
+// Queue is a FIFO ring buffer of entries read but not yet analyzed. Unlike
+// a channel, its capacity can change and what happens when it is full is
+// chosen by its Policy. It is safe for concurrent use by several pushers
+// and one consumer.
+type Queue struct {
+	mux     sync.Mutex
+	entries []models.LogEntry
+	head    int // Index of the oldest entry
+	count   int
+	policy  Policy
+	dropped int64
+
+	ready chan struct{} // Signalled when an entry is pushed, see Ready
+	space chan struct{} // Signalled when room is made, wakes a blocked Push
+}
+
+// New creates a queue holding up to capacity entries
+func New(capacity int, policy Policy) *Queue {
+	return &Queue{
+		entries: make([]models.LogEntry, max(1, capacity)),
+		policy:  policy,
+		ready:   make(chan struct{}, 1),
+		space:   make(chan struct{}, 1),
+	}
+}
+
+// Push adds an entry, applying the overflow policy if the queue is full.
+// With PolicyBlock it waits for room, and returns false if stop is closed
+// first; otherwise it always returns true, also when an entry is dropped.
+func (q *Queue) Push(entry models.LogEntry, stop <-chan struct{}) bool {
+	for {
+		q.mux.Lock()
+		if q.count < len(q.entries) {
+			q.push(entry)
+			q.mux.Unlock()
+			signal(q.ready)
+			return true
+		}
+
+		switch q.policy {
+		case PolicyDropOldest:
+			q.pop()
+			q.push(entry)
+			q.dropped++
+			q.mux.Unlock()
+			return true
+		case PolicyDropNewest:
+			q.dropped++
+			q.mux.Unlock()
+			return true
+		}
+		q.mux.Unlock()
+
+		select {
+		case <-stop:
+			return false
+		case <-q.space:
+		}
+	}
+}
+
+// TryPop removes and returns the oldest entry, or returns false at once if
+// the queue is empty
+func (q *Queue) TryPop() (models.LogEntry, bool) {
+	q.mux.Lock()
+	if q.count == 0 {
+		q.mux.Unlock()
+		return models.LogEntry{}, false
+	}
+	entry := q.pop()
+	q.mux.Unlock()
+
+	signal(q.space)
+	return entry, true
+}
+
+// Ready returns a channel that receives after entries are pushed, for the
+// consumer to wait on when TryPop finds the queue empty. A receive does not
+// guarantee an entry is still there.
+func (q *Queue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Len returns the number of entries waiting
+func (q *Queue) Len() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	return q.count
+}
+
+// Cap returns the number of entries the queue holds before the overflow
+// policy applies
+func (q *Queue) Cap() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	return len(q.entries)
+}
+
+// Resize changes the capacity, keeping the entries in order, and returns
+// the new capacity. It never drops below the number of entries waiting.
+func (q *Queue) Resize(capacity int) int {
+	q.mux.Lock()
+	capacity = max(1, capacity, q.count)
+	entries := make([]models.LogEntry, capacity)
+	for i := 0; i < q.count; i++ {
+		entries[i] = q.entries[(q.head+i)%len(q.entries)]
+	}
+	q.entries = entries
+	q.head = 0
+	q.mux.Unlock()
+
+	signal(q.space)
+	return capacity
+}
+
+// DroppedEntries returns how many entries the overflow policy dropped
+func (q *Queue) DroppedEntries() int64 {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	return q.dropped
+}
+
+// push appends an entry; q.mux must be held and the queue not full
+func (q *Queue) push(entry models.LogEntry) {
+	q.entries[(q.head+q.count)%len(q.entries)] = entry
+	q.count++
+}
+
+// pop removes the oldest entry; q.mux must be held and the queue not empty
+func (q *Queue) pop() models.LogEntry {
+	entry := q.entries[q.head]
+	q.entries[q.head] = models.LogEntry{} // Release the strings for the GC
+	q.head = (q.head + 1) % len(q.entries)
+	q.count--
+	return entry
+}
+
+// signal wakes a waiter on ch without blocking; a pending signal is enough
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// entry returns an entry told apart by its IP
+func entry(ip string) models.LogEntry {
+	return models.LogEntry{IP: ip, Level: "INFO", IsValid: true}
+}
+
+func TestPushReturnsWhenStoppedWhileFull(t *testing.T) {
+	q := New(2, PolicyBlock)
+	q.Push(entry("1"), nil)
+	q.Push(entry("2"), nil)
+
+	stop := make(chan struct{})
+	results := make(chan bool, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- q.Push(entry("blocked"), stop)
+		}()
+	}
+
+	// The pushers are blocked on the full queue until stopped
+	select {
+	case <-results:
+		t.Fatal("push returned while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(stop)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked pushes did not return after the stop")
+	}
+
+	close(results)
+	for ok := range results {
+		if ok {
+			t.Error("push stopped while blocked reported success")
+		}
+	}
+	if got := q.Len(); got != 2 {
+		t.Errorf("queue holds %d entries after the stop, want 2", got)
+	}
+}
+
+func TestPushWaitsForRoom(t *testing.T) {
+	q := New(1, PolicyBlock)
+	q.Push(entry("1"), nil)
+
+	pushed := make(chan bool)
+	go func() {
+		pushed <- q.Push(entry("2"), make(chan struct{}))
+	}()
+
+	if got, ok := q.TryPop(); !ok || got.IP != "1" {
+		t.Fatalf("popped %q, %v, want 1", got.IP, ok)
+	}
+	select {
+	case ok := <-pushed:
+		if !ok {
+			t.Fatal("push failed after room was made")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("push did not resume after room was made")
+	}
+	if got, ok := q.TryPop(); !ok || got.IP != "2" {
+		t.Errorf("popped %q, %v, want 2", got.IP, ok)
+	}
+}
+
+func TestDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   []string
+	}{
+		{PolicyDropOldest, []string{"2", "3"}},
+		{PolicyDropNewest, []string{"1", "2"}},
+	}
+
+	for _, tt := range tests {
+		q := New(2, tt.policy)
+		for _, ip := range []string{"1", "2", "3"} {
+			if !q.Push(entry(ip), nil) {
+				t.Fatalf("%s: push of %s failed", tt.policy, ip)
+			}
+		}
+
+		for _, want := range tt.want {
+			if got, ok := q.TryPop(); !ok || got.IP != want {
+				t.Errorf("%s: popped %q, %v, want %s", tt.policy, got.IP, ok, want)
+			}
+		}
+		if got := q.DroppedEntries(); got != 1 {
+			t.Errorf("%s: %d entries dropped, want 1", tt.policy, got)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// reader/fifo.go - Reading a named pipe (FIFO) across writer reconnects.
+
+package reader
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+)
+
+// isFIFO reports whether path is a named pipe
+func isFIFO(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode()&os.ModeNamedPipe != 0, nil
+}
+
+// checkFIFOs finds a named pipe among the log files. Only the last file
+// may be one, since a pipe is read until the reader is stopped. It returns
+// the pipe's path, or "" if there is none.
+func checkFIFOs(paths []string) (string, error) {
+	for i, path := range paths {
+		fifo, err := isFIFO(path)
+		if err != nil {
+			return "", err
+		}
+		if !fifo {
+			continue
+		}
+		if i < len(paths)-1 {
+			return "", fmt.Errorf("%s is a named pipe, which never ends, so it must be the last log file", path)
+		}
+		return path, nil
+	}
+	return "", nil
+}
+
+// readFIFO reads the named pipe at path until the reader is stopped. Any
+// number of producers may write to it; when the last one closes the pipe,
+// it is reopened, which waits for the next producer, instead of the EOF
+// ending the input. It returns false once the reader has been stopped.
+func (r *Reader) readFIFO(path string) bool {
+	for {
+		// Blocks until a producer opens the pipe, or Stop unblocks it
+		f, err := os.Open(path)
+		if err != nil {
+			log.Printf("Error opening named pipe: %v", err)
+			return true
+		}
+
+		select {
+		case <-r.stopChan:
+			f.Close()
+			return false
+		default:
+		}
+
+		r.path = path
+		r.file = f
+		r.input = f
+		ok := r.readInput(false)
+		f.Close()
+		if !ok {
+			return false
+		}
+
+		if r.debugMode {
+			r.debugLogger.Printf("All producers closed %s, reopening", path)
+		}
+	}
+}
+
+// unblockFIFO lets a readFIFO waiting for a producer see that the reader
+// has stopped, by briefly opening the pipe for writing itself
+func (r *Reader) unblockFIFO() {
+	// Without a waiting reader this fails right away instead of blocking
+	f, err := os.OpenFile(r.fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err == nil {
+		f.Close()
+	}
+}
@@ -0,0 +1,71 @@
+// reader/fingerprint.go - Groups similar error messages under one error type.
+
+package reader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FingerprintRule replaces one kind of variable token in an error type with
+// a placeholder, so e.g. "db timeout on shard 3" and "db timeout on shard 7"
+// share the fingerprint "db timeout on shard <num>"
+type FingerprintRule struct {
+	Name        string
+	re          *regexp.Regexp
+	placeholder string
+}
+
+// fingerprintRules are the known rules. They are applied in this order, so
+// broader tokens like UUIDs and IPs are replaced before the digits in them.
+var fingerprintRules = []FingerprintRule{
+	{"uuid", regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{"ip", regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b|\[[0-9a-fA-F:.]+\](?::\d+)?`), "<ip>"},
+	{"hex", regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b|\b[0-9a-fA-F]{8,}\b`), "<hex>"},
+	{"digits", regexp.MustCompile(`\d+(?:\.\d+)?`), "<num>"},
+}
+
+// ParseFingerprintRules parses a comma-separated list of rule names (uuid,
+// ip, hex, digits). The rules are always applied in their fixed order.
+func ParseFingerprintRules(spec string) ([]FingerprintRule, error) {
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, rule := range fingerprintRules {
+			if rule.Name == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown fingerprint rule %q (want uuid, ip, hex or digits)", name)
+		}
+		enabled[name] = true
+	}
+
+	var rules []FingerprintRule
+	for _, rule := range fingerprintRules {
+		if enabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// SetFingerprintRules sets the rules applied to every extracted error type
+func (r *Reader) SetFingerprintRules(rules []FingerprintRule) {
+	r.fingerprint = rules
+}
+
+// fingerprintOf applies the fingerprint rules to an error type
+func (r *Reader) fingerprintOf(errorType string) string {
+	for _, rule := range r.fingerprint {
+		errorType = rule.re.ReplaceAllString(errorType, rule.placeholder)
+	}
+	return errorType
+}
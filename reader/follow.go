@@ -0,0 +1,150 @@
+// reader/follow.go - Tail-style following of a log file that is still being written.
+
+package reader
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// followPollInterval is how long the reader waits at EOF before checking for new lines
+const followPollInterval = 250 * time.Millisecond
+
+// followLogs reads the file line by line and, on reaching EOF, polls for
+// appended data until the reader is stopped. Unlike bufio.Scanner it keeps
+// partial lines around so a line written in several chunks is not split.
+func (r *Reader) followLogs() {
+	br := bufio.NewReaderSize(r.file, 64*1024)
+	var pending []byte
+	discarding := false // Set while skipping the remainder of an oversized line
+	var head string     // Start of the oversized line being skipped
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !discarding {
+			pending = append(pending, chunk...)
+		}
+
+		switch err {
+		case nil:
+			if discarding {
+				discarding = false
+				if !r.skipLongLine(head) {
+					return
+				}
+				continue
+			}
+			if !r.handlePending(pending) {
+				return
+			}
+			pending = pending[:0]
+			continue
+		case bufio.ErrBufferFull:
+			if !discarding && len(pending) >= r.maxLineSize {
+				head = string(pending[:longLineHead])
+				pending = pending[:0]
+				discarding = true
+			}
+			continue
+		case io.EOF:
+			// Traces are usually written in one go, so the entry being
+			// assembled is complete once the writer pauses
+			if !r.flushHeld() {
+				return
+			}
+			// Wait for more data below
+		default:
+			if r.debugMode {
+				r.debugLogger.Printf("Follow error: %v", err)
+			}
+			log.Printf("Error reading input: %v", err)
+			return
+		}
+
+		select {
+		case <-r.stopChan:
+			return
+		case <-time.After(followPollInterval):
+		}
+
+		reopened, err := r.checkRotation()
+		if err != nil {
+			if r.debugMode {
+				r.debugLogger.Printf("Rotation check failed: %v", err)
+			}
+			continue
+		}
+		if reopened {
+			// Whatever was left of the old file will never be completed
+			if len(pending) > 0 && !discarding {
+				if !r.handlePending(pending) {
+					return
+				}
+			}
+			pending = pending[:0]
+			discarding = false
+			br.Reset(r.file)
+		}
+	}
+}
+
+// handlePending forwards a line read by followLogs. Lines that fit in the
+// read buffer but are longer than the maximum line size are skipped like
+// the scanner skips them. It returns false if the reader has been stopped.
+func (r *Reader) handlePending(pending []byte) bool {
+	if len(pending) > r.maxLineSize {
+		return r.skipLongLine(string(pending[:longLineHead]))
+	}
+	return r.handleLine(strings.TrimRight(string(pending), "\r\n"))
+}
+
+// checkRotation reports whether the followed file was rotated or truncated,
+// in which case reading restarts from the top of the current file at r.path.
+func (r *Reader) checkRotation() (bool, error) {
+	current, err := r.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// The file can briefly disappear mid-rotation; keep the old handle for now
+		return false, nil
+	}
+
+	// Rotated: the path now refers to a different inode
+	if !os.SameFile(current, info) {
+		f, err := os.Open(r.path)
+		if err != nil {
+			return false, err
+		}
+		r.file.Close()
+		r.file = f
+		r.input = f
+		if r.debugMode {
+			r.debugLogger.Printf("Reopened rotated file %s", r.path)
+		}
+		return true, nil
+	}
+
+	// Truncated: the file is now shorter than what we have already read
+	offset, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < offset {
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		if r.debugMode {
+			r.debugLogger.Printf("File %s truncated, reading from the top", r.path)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
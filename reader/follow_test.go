@@ -0,0 +1,62 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log_analyzer/models"
+	"log_analyzer/queue"
+)
+
+func TestMaxLineSizeWhenFollowing(t *testing.T) {
+	long := "[2024-03-01T12:00:00Z] INFO - IP:10.0.0.1 " + strings.Repeat("x", 1000)
+	input := long + "\n[2024-03-01T12:00:01Z] INFO - IP:10.0.0.2 short line\n"
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Following must skip the same lines as reading the file once
+	for _, follow := range []bool{false, true} {
+		entries := queue.New(16, queue.PolicyBlock)
+		r, err := NewFileReader(path, entries, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SetMaxLineSize(512); err != nil {
+			t.Fatal(err)
+		}
+		r.SetFollow(follow)
+		r.Start()
+
+		got := make([]models.LogEntry, 0, 2)
+		deadline := time.After(5 * time.Second)
+		for len(got) < 2 {
+			if entry, ok := entries.TryPop(); ok {
+				got = append(got, entry)
+				continue
+			}
+			select {
+			case <-entries.Ready():
+			case <-deadline:
+				t.Fatalf("follow %v: read %d entries, want 2", follow, len(got))
+			}
+		}
+		r.Stop()
+		<-r.Done()
+
+		if got[0].IsValid || got[0].Reject != models.RejectTooLong {
+			t.Errorf("follow %v: long line read as valid %v, reject %q, want %s", follow, got[0].IsValid, got[0].Reject, models.RejectTooLong)
+		}
+		if !strings.HasPrefix(got[0].OriginalLog, long[:longLineHead]) {
+			t.Errorf("follow %v: long line kept as %.40q..., want its start", follow, got[0].OriginalLog)
+		}
+		if !got[1].IsValid || got[1].IP != "10.0.0.2" {
+			t.Errorf("follow %v: short line read as %+v", follow, got[1])
+		}
+	}
+}
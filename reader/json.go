@@ -0,0 +1,123 @@
+// reader/json.go - Parsing of structured JSON log lines (one object per line).
+
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// JSONFields names the keys of a JSON log object that map to LogEntry fields
+type JSONFields struct {
+	Timestamp string
+	Level     string
+	IP        string
+	Message   string
+}
+
+// DefaultJSONFields returns the key names used when none are configured
+func DefaultJSONFields() JSONFields {
+	return JSONFields{
+		Timestamp: "ts",
+		Level:     "level",
+		IP:        "client_ip",
+		Message:   "msg",
+	}
+}
+
+// ParseJSONFields overrides the default key names from a spec such as
+// "timestamp=time,level=severity". Unlisted fields keep their defaults.
+func ParseJSONFields(spec string) (JSONFields, error) {
+	fields := DefaultJSONFields()
+	if spec == "" {
+		return fields, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return fields, fmt.Errorf("invalid field mapping %q, expected field=key", pair)
+		}
+		switch name {
+		case FieldTimestamp:
+			fields.Timestamp = key
+		case FieldLevel:
+			fields.Level = key
+		case FieldIP:
+			fields.IP = key
+		case FieldMessage:
+			fields.Message = key
+		default:
+			return fields, fmt.Errorf("unknown field %q", name)
+		}
+	}
+
+	return fields, nil
+}
+
+// SetJSONMode switches the reader to parsing each line as a JSON object,
+// using fields to locate the timestamp, level, IP and message. String
+// timestamps are parsed with the configured time layouts.
+func (r *Reader) SetJSONMode(fields JSONFields) {
+	r.jsonFields = fields
+	r.parser = ParserFunc(r.parseJSON)
+}
+
+// parseJSON is the parser of JSON log lines. Malformed JSON or a missing
+// timestamp rejects the line.
+func (r *Reader) parseJSON(line string) (models.LogEntry, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return models.LogEntry{}, Reject(models.RejectBadJSON)
+	}
+
+	timestamp, ok := r.parseJSONTimestamp(obj[r.jsonFields.Timestamp])
+	if !ok {
+		return models.LogEntry{}, Reject(models.RejectBadTimestamp)
+	}
+
+	level, _ := obj[r.jsonFields.Level].(string)
+	entry := models.LogEntry{
+		Timestamp: timestamp,
+		Level:     strings.ToUpper(level),
+	}
+	if ip, ok := obj[r.jsonFields.IP]; ok && ip != nil {
+		entry.IP = normalizeIP(fmt.Sprint(ip))
+	}
+
+	message, _ := obj[r.jsonFields.Message].(string)
+	r.setMessage(&entry, message)
+
+	return entry, nil
+}
+
+// parseJSONTimestamp accepts a string in one of the configured time layouts
+// or Unix epoch seconds, given either as a JSON number or a numeric string
+func (r *Reader) parseJSONTimestamp(value interface{}) (time.Time, bool) {
+	var epoch string
+	switch v := value.(type) {
+	case json.Number:
+		epoch = v.String()
+	case string:
+		epoch = v
+	default:
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseFloat(epoch, 64)
+	if err != nil {
+		// Not a number, so it must be a formatted timestamp
+		return r.parseTimestamp(epoch)
+	}
+	sec := int64(seconds)
+	nsec := int64((seconds - float64(sec)) * float64(time.Second))
+	return time.Unix(sec, nsec).UTC(), true
+}
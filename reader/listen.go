@@ -0,0 +1,261 @@
+// reader/listen.go - Receiving log lines over UDP datagrams or line-delimited TCP connections.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"log_analyzer/queue"
+)
+
+// maxDatagramSize is the largest UDP datagram the reader will accept
+const maxDatagramSize = 64 * 1024
+
+// udpReadBuffer is the socket receive buffer requested for UDP input, so
+// a burst of datagrams is queued rather than dropped by the kernel
+const udpReadBuffer = 4 * 1024 * 1024
+
+// acceptRetryDelay is how long the reader waits after a failed accept,
+// e.g. when out of file descriptors, before accepting again
+const acceptRetryDelay = 100 * time.Millisecond
+
+// ParseListenAddr splits a listen address such as "udp://:5140" or
+// "tcp://127.0.0.1:6000" into its network and host:port
+func ParseListenAddr(addr string) (string, string, error) {
+	network, hostPort, ok := strings.Cut(addr, "://")
+	if !ok {
+		return "", "", fmt.Errorf("listen address %q must look like udp://host:port or tcp://host:port", addr)
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return "", "", fmt.Errorf("unknown network %q (want udp or tcp)", network)
+	}
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		return "", "", fmt.Errorf("listen address %q: %w", addr, err)
+	}
+	return network, hostPort, nil
+}
+
+// NewListenReader creates a new Reader that receives log lines from the
+// network at addr, e.g. "udp://:5140" for a syslog-style feed with one or
+// more lines per datagram, or "tcp://:6000" for any number of concurrent
+// clients each sending newline-delimited lines. The socket is bound right
+// away, so a bad or busy address fails here rather than once started.
+// Lines from all clients are parsed in arrival order; the input never
+// ends, so the reader runs until stopped.
+func NewListenReader(addr string, entries *queue.Queue, debugMode bool) (*Reader, error) {
+	network, hostPort, err := ParseListenAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(nil, entries, debugMode)
+	if strings.HasPrefix(network, "udp") {
+		r.packetConn, err = net.ListenPacket(network, hostPort)
+	} else {
+		r.listener, err = net.Listen(network, hostPort)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if udp, ok := r.packetConn.(*net.UDPConn); ok {
+		// Best effort, the kernel may cap it
+		udp.SetReadBuffer(udpReadBuffer)
+	}
+	r.conns = make(map[net.Conn]struct{})
+	return r, nil
+}
+
+// Addr returns the address the reader listens on, which tells the port
+// chosen for ":0", or nil if it does not read from the network
+func (r *Reader) Addr() net.Addr {
+	switch {
+	case r.packetConn != nil:
+		return r.packetConn.LocalAddr()
+	case r.listener != nil:
+		return r.listener.Addr()
+	default:
+		return nil
+	}
+}
+
+// listening reports whether the reader receives its input from the network
+func (r *Reader) listening() bool {
+	return r.packetConn != nil || r.listener != nil
+}
+
+// serveNetwork receives lines until the reader is stopped. Every socket
+// goroutine hands its lines to this one, which parses them, so the
+// reader's state is never touched concurrently.
+func (r *Reader) serveNetwork() {
+	lines := make(chan rawLine, 1024)
+	var wg sync.WaitGroup
+
+	// Closing the sockets unblocks the goroutines reading from them
+	go func() {
+		<-r.stopChan
+		r.closeNetwork()
+	}()
+
+	wg.Add(1)
+	if r.packetConn != nil {
+		go r.readPackets(lines, &wg)
+	} else {
+		go r.acceptConns(lines, &wg)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	for line := range lines {
+		ok := false
+		if line.tooLong {
+			ok = r.skipLongLine(line.text)
+		} else {
+			ok = r.handleLine(line.text)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// readPackets reads UDP datagrams, each holding one or more lines
+func (r *Reader) readPackets(lines chan<- rawLine, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := r.packetConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Error reading UDP input: %v", err)
+			}
+			return
+		}
+		if r.debugMode {
+			r.debugLogger.Printf("Received %d byte datagram from %s", n, from)
+		}
+
+		datagram := strings.TrimRight(string(buf[:n]), "\r\n")
+		for _, text := range strings.Split(datagram, "\n") {
+			line := rawLine{text: strings.TrimSuffix(text, "\r")}
+			if len(line.text) >= r.maxLineSize {
+				line = rawLine{text: line.text[:longLineHead], tooLong: true}
+			}
+			if !r.sendLine(lines, line) {
+				return
+			}
+		}
+	}
+}
+
+// acceptConns accepts TCP clients and reads each on its own goroutine
+func (r *Reader) acceptConns(lines chan<- rawLine, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Error accepting TCP connection: %v", err)
+			time.Sleep(acceptRetryDelay)
+			continue
+		}
+
+		if !r.trackConn(conn) {
+			conn.Close()
+			return
+		}
+		wg.Add(1)
+		go r.readConn(conn, lines, wg)
+	}
+}
+
+// readConn reads the lines a TCP client sends until it disconnects
+func (r *Reader) readConn(conn net.Conn, lines chan<- rawLine, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer r.untrackConn(conn)
+
+	if r.debugMode {
+		r.debugLogger.Printf("Client %s connected", conn.RemoteAddr())
+	}
+
+	scanner, splitter := r.newScanner(conn)
+	for scanner.Scan() {
+		if !r.sendLine(lines, rawLine{text: scanner.Text(), tooLong: splitter.tooLong}) {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		if r.debugMode {
+			r.debugLogger.Printf("Error reading from client %s: %v", conn.RemoteAddr(), err)
+		}
+		log.Printf("Error reading TCP input: %v", err)
+	}
+	if r.debugMode {
+		r.debugLogger.Printf("Client %s disconnected", conn.RemoteAddr())
+	}
+}
+
+// sendLine hands a line to serveNetwork. It returns false if the reader
+// has been stopped.
+func (r *Reader) sendLine(lines chan<- rawLine, line rawLine) bool {
+	select {
+	case <-r.stopChan:
+		return false
+	case lines <- line:
+		return true
+	}
+}
+
+// trackConn records an open client connection so closeNetwork can close
+// it. It returns false if the reader is already closing its sockets.
+func (r *Reader) trackConn(conn net.Conn) bool {
+	r.connMux.Lock()
+	defer r.connMux.Unlock()
+
+	if r.conns == nil {
+		return false
+	}
+	r.conns[conn] = struct{}{}
+	return true
+}
+
+// untrackConn closes a client connection and forgets it
+func (r *Reader) untrackConn(conn net.Conn) {
+	r.connMux.Lock()
+	defer r.connMux.Unlock()
+
+	conn.Close()
+	delete(r.conns, conn)
+}
+
+// closeNetwork closes the listening socket and every client connection
+func (r *Reader) closeNetwork() {
+	if r.packetConn != nil {
+		r.packetConn.Close()
+	}
+	if r.listener != nil {
+		r.listener.Close()
+	}
+
+	r.connMux.Lock()
+	defer r.connMux.Unlock()
+
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.conns = nil
+}
@@ -0,0 +1,112 @@
+// reader/longlines.go - Skipping lines longer than the maximum line size instead of failing the read.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"log_analyzer/models"
+)
+
+// DefaultMaxLineSize is the longest line the reader accepts unless
+// configured otherwise
+const DefaultMaxLineSize = 1024 * 1024
+
+// longLineHead is how much of an oversized line is kept, for the reject
+// file and debug log
+const longLineHead = 256
+
+// SetMaxLineSize sets the longest line, in bytes, the reader accepts.
+// Longer lines are skipped and counted as invalid (too_long) rather than
+// aborting the read; with varint framing a longer record still ends it.
+func (r *Reader) SetMaxLineSize(n int) error {
+	if n < longLineHead {
+		return fmt.Errorf("maximum line size must be at least %d bytes, got %d", longLineHead, n)
+	}
+	r.maxLineSize = n
+	return nil
+}
+
+// newScanner returns a scanner splitting input into records by the
+// reader's framing. With newline framing, check the returned splitter's
+// tooLong after each token.
+func (r *Reader) newScanner(input io.Reader) (*bufio.Scanner, *lineSplitter) {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, min(64*1024, r.maxLineSize)), r.maxLineSize) // Larger buffer for high volume
+
+	splitter := &lineSplitter{max: r.maxLineSize}
+	if r.framing == FramingVarint {
+		scanner.Split(r.splitVarint)
+	} else {
+		scanner.Split(splitter.split)
+	}
+	return scanner, splitter
+}
+
+// lineSplitter splits input into lines like bufio.ScanLines, except that a
+// line of max bytes or more does not fail the scan with bufio.ErrTooLong:
+// its start is returned as the token, with tooLong set, and the rest is
+// skipped
+type lineSplitter struct {
+	max        int
+	head       []byte // Start of the oversized line being skipped
+	discarding bool   // Set while skipping the rest of an oversized line
+	tooLong    bool   // Set when the last token is the start of an oversized line
+}
+
+func (s *lineSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	s.tooLong = false
+
+	if s.discarding {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 && !atEOF {
+			return len(data), nil, nil
+		}
+		advance := i + 1
+		if i < 0 {
+			advance = len(data)
+		}
+		s.discarding = false
+		s.tooLong = true
+		return advance, s.head, nil
+	}
+
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance == 0 && token == nil && err == nil && len(data) >= s.max {
+		// The scanner's buffer is full without a line end in sight
+		s.head = append(s.head[:0], data[:longLineHead]...)
+		s.discarding = true
+		return len(data), nil, nil
+	}
+	return advance, token, err
+}
+
+// skipLongLine forwards an oversized line, of which only head was kept, to
+// the analyzer as an invalid entry. It ends any entry being assembled from
+// continuation lines. It returns false if the reader has been stopped.
+func (r *Reader) skipLongLine(head string) bool {
+	if r.debugMode {
+		r.debugLogger.Printf("Skipping line longer than %d bytes: %s...", r.maxLineSize, head)
+	}
+	if !r.flushHeld() {
+		return false
+	}
+	if r.jobs != nil {
+		return r.dispatchLine(rawLine{text: head, tooLong: true})
+	}
+
+	select {
+	case <-r.stopChan:
+		return false
+	default:
+		return r.emit(longLineEntry(head))
+	}
+}
+
+// longLineEntry is the invalid entry standing in for an oversized line
+func longLineEntry(head string) models.LogEntry {
+	return models.LogEntry{OriginalLog: head + "...", Reject: models.RejectTooLong}
+}
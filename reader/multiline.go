@@ -0,0 +1,71 @@
+// reader/multiline.go - Joining stack traces and other continuation lines into one entry.
+
+package reader
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SetMultiline joins continuation lines, such as the frames of a Java or
+// Python stack trace, onto the entry before them instead of counting each
+// as a malformed entry. Lines matching continuation are continuations; with
+// a nil continuation, any non-empty line the line pattern does not match
+// (or, for syslog, without a priority) is one. The joined entry keeps the
+// first line's timestamp and level, with the continuation lines appended
+// to its message. Only text lines are joined, not JSON or decoded records.
+func (r *Reader) SetMultiline(continuation *regexp.Regexp) {
+	r.multiline = true
+	r.continuationRe = continuation
+}
+
+// isContinuation reports whether line continues the entry before it
+func (r *Reader) isContinuation(line string) bool {
+	if r.continuationRe != nil {
+		return r.continuationRe.MatchString(line)
+	}
+	if r.syslogFormat != "" {
+		_, _, ok := syslogPriority(line)
+		return line != "" && !ok
+	}
+	return line != "" && !r.pattern.MatchString(line)
+}
+
+// joinLine adds line to the entry being assembled. When line starts a new
+// entry, it returns the previous one, which is then complete.
+func (r *Reader) joinLine(line string) (string, bool) {
+	if r.holding && r.isContinuation(line) {
+		// Bound the joined entry like a single line; a runaway trace loses its tail
+		if len(r.held)+len(line) < r.maxLineSize {
+			r.held += "\n" + line
+		} else if r.debugMode {
+			r.debugLogger.Printf("Dropping continuation line past %d bytes", r.maxLineSize)
+		}
+		return "", false
+	}
+
+	record, ready := r.held, r.holding
+	r.held, r.holding = line, true
+	return record, ready
+}
+
+// flushHeld forwards the entry being assembled, since no more continuation
+// lines will follow it. It returns false if the reader has been stopped.
+func (r *Reader) flushHeld() bool {
+	if !r.holding {
+		return true
+	}
+	record := r.held
+	r.held, r.holding = "", false
+	return r.handleRecord(record)
+}
+
+// splitContinuation separates the first line of a joined entry, which is
+// parsed, from its continuation lines, which are appended to the message
+func (r *Reader) splitContinuation(record string) (string, string) {
+	if !r.multiline {
+		return record, ""
+	}
+	first, rest, _ := strings.Cut(record, "\n")
+	return first, rest
+}
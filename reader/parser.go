@@ -0,0 +1,131 @@
+// reader/parser.go - Pluggable line parsers and a registry of named ones.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"log_analyzer/models"
+)
+
+// Parser turns one line into a log entry. The built-in text, JSON and
+// syslog modes and decoders are all Parsers; SetParser replaces them with
+// a custom one. Lines are never empty. For a line it cannot parse, a parser
+// returns an error, a *RejectError (see Reject) to give the reason the line
+// is counted under, or any other error for models.RejectBadRecord.
+//
+// The reader checks the entry's level is accepted, marks it valid and
+// fills in OriginalLog if the parser left it empty. Everything else,
+// including the error type and duration, is used as returned.
+type Parser interface {
+	Parse(line string) (models.LogEntry, error)
+}
+
+// ParserFunc adapts a function to a Parser
+type ParserFunc func(line string) (models.LogEntry, error)
+
+// Parse calls f(line)
+func (f ParserFunc) Parse(line string) (models.LogEntry, error) {
+	return f(line)
+}
+
+// RejectError is returned by a Parser for a line it rejects
+type RejectError struct {
+	Reason string // One of the models.Reject* reasons, or a custom one
+}
+
+func (e *RejectError) Error() string {
+	return "line rejected: " + e.Reason
+}
+
+// Reject returns a *RejectError for reason
+func Reject(reason string) error {
+	return &RejectError{Reason: reason}
+}
+
+var (
+	parsersMux sync.RWMutex
+	parsers    = make(map[string]func() Parser)
+)
+
+// RegisterParser makes a parser available under name, e.g. from the init
+// function of the package implementing it, so programs can pick it by name
+// with LookupParser. newParser is called for every lookup.
+func RegisterParser(name string, newParser func() Parser) error {
+	if name == "" || newParser == nil {
+		return errors.New("parser needs a name and a constructor")
+	}
+
+	parsersMux.Lock()
+	defer parsersMux.Unlock()
+
+	if _, ok := parsers[name]; ok {
+		return fmt.Errorf("parser %q is already registered", name)
+	}
+	parsers[name] = newParser
+	return nil
+}
+
+// LookupParser creates the parser registered under name
+func LookupParser(name string) (Parser, error) {
+	parsersMux.RLock()
+	newParser, ok := parsers[name]
+	parsersMux.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown parser %q (registered: %v)", name, Parsers())
+	}
+	return newParser(), nil
+}
+
+// Parsers returns the names of the registered parsers, sorted
+func Parsers() []string {
+	parsersMux.RLock()
+	defer parsersMux.RUnlock()
+
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetParser replaces the built-in parsing with parser. Entries must still
+// have an accepted level, see SetLevels.
+func (r *Reader) SetParser(parser Parser) {
+	r.parser = parser
+}
+
+// parseLine turns a line into an entry with the configured parser. An
+// empty line, a parser error or an unaccepted level gives an invalid entry
+// carrying the reject reason.
+func (r *Reader) parseLine(line string) models.LogEntry {
+	// Handle empty lines and completely malformed entries gracefully
+	if line == "" {
+		return models.LogEntry{Reject: models.RejectEmpty}
+	}
+
+	entry, err := r.parser.Parse(line)
+	if entry.OriginalLog == "" {
+		entry.OriginalLog = line
+	}
+	if err != nil {
+		reason := models.RejectBadRecord
+		var reject *RejectError
+		if errors.As(err, &reject) {
+			reason = reject.Reason
+		}
+		return models.LogEntry{OriginalLog: entry.OriginalLog, Reject: reason}
+	}
+	if !r.levels[entry.Level] {
+		return models.LogEntry{OriginalLog: entry.OriginalLog, Reject: models.RejectUnknownLevel}
+	}
+
+	entry.IsValid = true
+	entry.Reject = ""
+	return entry
+}
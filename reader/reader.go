@@ -1,38 +1,185 @@
-// reader/reader.go - Reads log entries from stdin and sends them to a channel for processing.
+// reader/reader.go - Reads log entries from stdin, files or the network and pushes them onto a queue for processing.
 
 package reader
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/netip"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"log_analyzer/models"
+	"log_analyzer/queue"
+)
+
+// gzipMagic is the header every gzip stream starts with
+const gzipMagic = "\x1f\x8b"
+
+// Field names used to map capture groups of the log pattern to LogEntry fields
+const (
+	FieldTimestamp = "timestamp"
+	FieldLevel     = "level"
+	FieldIP        = "ip"
+	FieldMessage   = "message"
 )
 
 var (
-	logRegex   = regexp.MustCompile(`\[(.*?)\] (ERROR|INFO|DEBUG) - IP:([\d\.]+)(?: (.*))?`)
+	// DefaultLevels are the log levels accepted when none are configured
+	DefaultLevels = []string{"FATAL", "ERROR", "WARN", "INFO", "DEBUG"}
+
+	// logRegex matches any level word, so lines with a level that is not
+	// accepted are rejected as unknown_level rather than as a regex miss
+	logRegex   = regexp.MustCompile(`\[(.*?)\] ([A-Za-z]+) - IP:(\[[^\]\s]+\](?::\d+)?|[0-9A-Fa-f:.]+)(?: (.*))?`)
 	errorRegex = regexp.MustCompile(`Error 500 - (.*)`)
+
+	// DefaultTimeLayouts are tried in order when parsing timestamps
+	// The first takes a zone so a trailing Z stays UTC with SetLocation.
+	DefaultTimeLayouts = []string{"2006-01-02T15:04:05Z07:00", time.RFC3339Nano}
+
+	// defaultFieldMap maps the fields to the capture groups of logRegex
+	defaultFieldMap = map[string]int{
+		FieldTimestamp: 1,
+		FieldLevel:     2,
+		FieldIP:        3,
+		FieldMessage:   4,
+	}
 )
 
-// Reader reads log entries from stdin
+// Reader reads log entries from stdin or a log file
 type Reader struct {
-	logChan     chan models.LogEntry
-	stopChan    chan struct{}
-	debugMode   bool
-	debugLogger *log.Logger
+	input        io.Reader
+	paths        []string // Log files read in order, empty when reading stdin
+	path         string   // Path of the file currently being read
+	file         *os.File // Set when the reader owns the input (e.g. an opened file)
+	follow       bool     // Keep watching the file for appended lines after EOF
+	fifo         string   // Path of the last file if it is a named pipe (see fifo.go)
+	pattern      *regexp.Regexp
+	fieldMap     map[string]int    // Field name -> capture group index in pattern
+	extraFields  map[string]int    // Other named groups of pattern, copied into LogEntry.Fields
+	levels       map[string]bool   // Accepted log levels; anything else is invalid
+	errorRegexes []*regexp.Regexp  // Tried in order to extract ErrorType from ERROR messages
+	fingerprint  []FingerprintRule // Applied to each ErrorType, see fingerprint.go
+	jsonFields   JSONFields
+	syslogFormat SyslogFormat    // Parse each line as syslog when set (see syslog.go)
+	timeLayouts  []string        // Layouts tried in order when parsing timestamps
+	location     *time.Location  // Zone of timestamps without an offset, nil for the defaults (see SetLocation)
+	durationRe   *regexp.Regexp  // Extracts a duration in milliseconds from messages, if set
+	replaySpeed  float64         // Paces entries by their timestamps when above 0 (see replay.go)
+	replayLast   time.Time       // Latest timestamp replayed so far
+	minSeverity  models.Severity // Valid entries below this are marked Filtered
+	include      *regexp.Regexp  // If set, valid lines not matching it are marked Filtered
+	exclude      *regexp.Regexp  // If set, valid lines matching it are marked Filtered
+	since        time.Time       // If set, valid entries logged before it are marked Filtered
+	until        time.Time       // If set, valid entries logged at or after it are marked Filtered
+	rejects      *rejectFile     // Optional file receiving lines that fail to parse
+	parser       Parser          // Turns lines into entries, parseText unless replaced (see parser.go)
+	framing      Framing         // How the input is split into records
+	maxLineSize  int             // Longer lines are skipped (see longlines.go)
+	queue        *queue.Queue    // Receives the entries, see queue.Policy for when it is full
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+	doneChan     chan struct{} // Closed once the input has been fully consumed
+	debugMode    bool
+	debugLogger  *log.Logger
+
+	// Joining of continuation lines, used when multiline is set (see multiline.go)
+	multiline      bool
+	continuationRe *regexp.Regexp // Lines matching it are continuations; nil uses pattern
+	held           string         // Entry being assembled from continuation lines
+	holding        bool           // Set while held has not been forwarded
+
+	// Network input, used by NewListenReader (see listen.go)
+	packetConn net.PacketConn        // Set when listening for UDP datagrams
+	listener   net.Listener          // Set when accepting TCP clients
+	conns      map[net.Conn]struct{} // Open TCP clients, nil once closed
+	connMux    sync.Mutex
+
+	// Parser pool, used when parserWorkers > 1 (see workers.go)
+	parserWorkers int
+	jobs          chan rawLine
+	nextSeq       uint64
+}
+
+// NewReader creates a new Reader that reads from stdin
+func NewReader(entries *queue.Queue, debugMode bool) *Reader {
+	return newReader(os.Stdin, entries, debugMode)
+}
+
+// NewStreamReader creates a new Reader that reads from input, e.g. a
+// network connection or a pipe owned by an embedding program
+func NewStreamReader(input io.Reader, entries *queue.Queue, debugMode bool) *Reader {
+	return newReader(input, entries, debugMode)
 }
 
-// NewReader creates a new Reader
-func NewReader(logChan chan models.LogEntry, debugMode bool) *Reader {
+// NewFileReader creates a new Reader that reads from the log file at path
+func NewFileReader(path string, entries *queue.Queue, debugMode bool) (*Reader, error) {
+	return NewFilesReader([]string{path}, entries, debugMode)
+}
+
+// NewFilesReader creates a new Reader that reads the given log files one
+// after another as a single stream, e.g. rotated logs from oldest to newest.
+// The last file may be a named pipe, which is read until the reader is
+// stopped, across any number of producers (see readFIFO).
+func NewFilesReader(paths []string, entries *queue.Queue, debugMode bool) (*Reader, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no log files given")
+	}
+
+	// Fail fast on missing files rather than partway through the stream
+	fifo, err := checkFIFOs(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	// Opening a named pipe waits for a producer, so readFIFO opens it later
+	if fifo == paths[0] {
+		r := newReader(nil, entries, debugMode)
+		r.paths = paths
+		r.fifo = fifo
+		return r, nil
+	}
+
+	f, err := os.Open(paths[0])
+	if err != nil {
+		return nil, err
+	}
+	r := newReader(f, entries, debugMode)
+	r.paths = paths
+	r.path = paths[0]
+	r.file = f
+	r.fifo = fifo
+	return r, nil
+}
+
+func newReader(input io.Reader, entries *queue.Queue, debugMode bool) *Reader {
 	r := &Reader{
-		logChan:   logChan,
-		stopChan:  make(chan struct{}),
-		debugMode: debugMode,
+		input:        input,
+		pattern:      logRegex,
+		fieldMap:     defaultFieldMap,
+		levels:       levelSet(DefaultLevels),
+		errorRegexes: []*regexp.Regexp{errorRegex},
+		timeLayouts:  DefaultTimeLayouts,
+		minSeverity:  models.SeverityDebug, // Filter nothing by level
+		framing:      FramingNewline,
+		maxLineSize:  DefaultMaxLineSize,
+		queue:        entries,
+		stopChan:     make(chan struct{}),
+		doneChan:     make(chan struct{}),
+		debugMode:    debugMode,
 	}
-	
+
+	r.parser = ParserFunc(r.parseText)
+
 	if debugMode {
 		f, err := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -40,81 +187,492 @@ func NewReader(logChan chan models.LogEntry, debugMode bool) *Reader {
 		}
 		r.debugLogger = log.New(f, "READER: ", log.LstdFlags)
 	}
-	
+
 	return r
 }
 
-// Start begins reading from stdin
+// SetFollow enables tail-style following of a file input. At EOF of the
+// last file the reader waits for new lines instead of finishing, reopening
+// the file if it is rotated or truncated. It has no effect when reading stdin.
+func (r *Reader) SetFollow(follow bool) {
+	r.follow = follow
+}
+
+// SetPattern replaces the regex used to parse log lines. fieldMap maps the
+// Field* names to capture group indices in re; timestamp and level are
+// required, ip and message are optional. Any other named group, e.g.
+// (?P<status>\d+), is copied into the entry's Fields when it matches.
+func (r *Reader) SetPattern(re *regexp.Regexp, fieldMap map[string]int) error {
+	for _, field := range []string{FieldTimestamp, FieldLevel} {
+		if _, ok := fieldMap[field]; !ok {
+			return fmt.Errorf("pattern has no %s group", field)
+		}
+	}
+	for field, group := range fieldMap {
+		if group < 1 || group > re.NumSubexp() {
+			return fmt.Errorf("group %d for field %s is out of range (pattern has %d groups)",
+				group, field, re.NumSubexp())
+		}
+	}
+
+	r.pattern = re
+	r.fieldMap = fieldMap
+	r.extraFields = extraFieldsFromNames(re)
+	return nil
+}
+
+// SetErrorPatterns replaces the regexes used to extract the error type from
+// ERROR messages. They are tried in order and the first match wins; the
+// error type is the first capture group, or the whole match if there is none.
+func (r *Reader) SetErrorPatterns(patterns []*regexp.Regexp) {
+	if len(patterns) > 0 {
+		r.errorRegexes = patterns
+	}
+}
+
+// SetLevels sets the accepted log levels, e.g. ERROR, WARN, INFO, DEBUG.
+// Entries whose level is not listed are marked invalid.
+func (r *Reader) SetLevels(levels []string) {
+	if len(levels) == 0 {
+		return
+	}
+
+	r.levels = levelSet(levels)
+}
+
+// SetMinLevel marks valid entries less severe than level (e.g. INFO and
+// DEBUG for "WARN") as filtered, so the analyzer counts them without
+// windowing them. Unknown level names are refused, see models.ParseLevel.
+func (r *Reader) SetMinLevel(level string) error {
+	severity, err := models.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	r.minSeverity = severity
+	return nil
+}
+
+// SetLineFilters restricts the analysis to valid lines matching include
+// and not matching exclude; either may be nil. The filters compose with the
+// minimum level, and lines they reject are marked as filtered.
+func (r *Reader) SetLineFilters(include, exclude *regexp.Regexp) {
+	r.include = include
+	r.exclude = exclude
+}
+
+// SetTimeRange restricts the analysis to valid entries whose own
+// timestamp is at or after since and before until; a zero time leaves that
+// end open. Entries outside the range are marked as filtered.
+func (r *Reader) SetTimeRange(since, until time.Time) {
+	r.since = since
+	r.until = until
+}
+
+// filtered reports whether a valid entry is rejected by the level, time or line filters
+func (r *Reader) filtered(entry models.LogEntry) bool {
+	if models.LevelSeverity(entry.Level) < r.minSeverity {
+		return true
+	}
+	if !r.since.IsZero() && entry.Timestamp.Before(r.since) {
+		return true
+	}
+	if !r.until.IsZero() && !entry.Timestamp.Before(r.until) {
+		return true
+	}
+	if r.include != nil && !r.include.MatchString(entry.OriginalLog) {
+		return true
+	}
+	return r.exclude != nil && r.exclude.MatchString(entry.OriginalLog)
+}
+
+// SetTimeLayouts sets the Go time layouts tried, in order, when parsing
+// timestamps. An entry whose timestamp matches none of them is invalid.
+func (r *Reader) SetTimeLayouts(layouts []string) {
+	if len(layouts) > 0 {
+		r.timeLayouts = layouts
+	}
+}
+
+// SetLocation sets the zone timestamps without an offset are read in, e.g.
+// from a -time-layout without one or RFC 3164 syslog. By default layouts
+// read them as UTC and syslog as local time. Timestamps that carry an
+// offset or a Z are unaffected.
+func (r *Reader) SetLocation(loc *time.Location) {
+	r.location = loc
+}
+
+// SetDurationPattern enables extraction of a request duration in
+// milliseconds from each entry's message. The first capture group of re
+// must match the numeric value, e.g. `took (\d+(?:\.\d+)?)ms`.
+func (r *Reader) SetDurationPattern(re *regexp.Regexp) error {
+	if re.NumSubexp() < 1 {
+		return fmt.Errorf("duration pattern needs a capture group for the value")
+	}
+	r.durationRe = re
+	return nil
+}
+
+// FieldMapFromNames builds a field map from the named capture groups of re,
+// e.g. (?P<timestamp>...) or (?P<level>...)
+func FieldMapFromNames(re *regexp.Regexp) map[string]int {
+	fieldMap := make(map[string]int)
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case FieldTimestamp, FieldLevel, FieldIP, FieldMessage:
+			fieldMap[name] = i
+		}
+	}
+	return fieldMap
+}
+
+// extraFieldsFromNames returns the named capture groups of re other than
+// the Field* names, or nil if there are none
+func extraFieldsFromNames(re *regexp.Regexp) map[string]int {
+	var fields map[string]int
+	for i, name := range re.SubexpNames() {
+		switch name {
+		case "", FieldTimestamp, FieldLevel, FieldIP, FieldMessage:
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]int)
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// Start begins reading from the input
 func (r *Reader) Start() {
+	if r.parserWorkers > 1 {
+		r.startParserPool()
+	}
+	if r.rejects != nil {
+		go r.flushRejects()
+	}
 	go r.readLogs()
 }
 
-// Stop signals the reader to stop
+// StartContext begins reading like Start, and stops the reader once ctx
+// is cancelled
+func (r *Reader) StartContext(ctx context.Context) {
+	r.Start()
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.Stop()
+		case <-r.stopChan:
+		}
+	}()
+}
+
+// Stop signals the reader to stop. It may be called more than once.
 func (r *Reader) Stop() {
-	close(r.stopChan)
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		if r.fifo != "" {
+			r.unblockFIFO()
+		}
+
+		// Flush synchronously, the process may exit right after Stop returns
+		if r.rejects != nil {
+			r.closeRejects()
+		}
+	})
+}
+
+// Done returns a channel that is closed once the reader reaches the end of its input
+func (r *Reader) Done() <-chan struct{} {
+	return r.doneChan
 }
 
 func (r *Reader) readLogs() {
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // Larger buffer for high volume
+	if r.jobs != nil {
+		// The parser pool closes doneChan once every dispatched line is forwarded
+		defer close(r.jobs)
+	} else {
+		defer close(r.doneChan)
+	}
 
-	for scanner.Scan() {
-		select {
-		case <-r.stopChan:
+	if r.listening() {
+		r.serveNetwork()
+		return
+	}
+	if len(r.paths) == 0 {
+		r.readInput(false)
+		return
+	}
+
+	for i, path := range r.paths {
+		if path == r.fifo && i == len(r.paths)-1 {
+			r.readFIFO(path)
 			return
-		default:
-			logText := scanner.Text()
-			entry := r.parseLine(logText)
-			if r.debugMode && !entry.IsValid {
-				r.debugLogger.Printf("Skipped malformed entry: %s", logText)
+		}
+		if i > 0 {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Printf("Error opening log file: %v", err)
+				continue
 			}
-			r.logChan <- entry
+			r.path = path
+			r.file = f
+			r.input = f
+		}
+
+		// Only the newest (last) file can still be growing
+		follow := r.follow && i == len(r.paths)-1
+		ok := r.readInput(follow)
+
+		// The file may have been swapped out by followLogs, so close whichever is current
+		r.file.Close()
+		if !ok {
+			return
 		}
 	}
+}
+
+// readInput reads the current input to the end, or until stopped when
+// following. It returns false if the reader has been stopped.
+func (r *Reader) readInput(follow bool) bool {
+	// A compressed file cannot be followed, it is read to the end like stdin
+	if follow && r.file != nil && !isGzipFile(r.file) {
+		r.followLogs()
+		return false
+	}
+
+	input, err := decompress(r.input)
+	if err != nil {
+		if r.debugMode {
+			r.debugLogger.Printf("Decompression error: %v", err)
+		}
+		log.Printf("Error reading gzip input: %v", err)
+		return true
+	}
+
+	scanner, splitter := r.newScanner(input)
+	for scanner.Scan() {
+		ok := false
+		if splitter.tooLong {
+			ok = r.skipLongLine(scanner.Text())
+		} else {
+			ok = r.handleLine(scanner.Text())
+		}
+		if !ok {
+			return false
+		}
+	}
+	// A trace never continues into the next file
+	if !r.flushHeld() {
+		return false
+	}
 
 	if err := scanner.Err(); err != nil {
 		if r.debugMode {
 			r.debugLogger.Printf("Scanner error: %v", err)
 		}
-		log.Printf("Error reading stdin: %v", err)
+		log.Printf("Error reading input: %v", err)
+	}
+	return true
+}
+
+// decompress wraps input in a gzip reader if it starts with the gzip magic
+// bytes, so archived logs can be read without piping them through zcat
+func decompress(input io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(input)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil || string(magic) != gzipMagic {
+		// Too short or not gzip; read it as plain text
+		return buffered, nil
+	}
+
+	return gzip.NewReader(buffered)
+}
+
+// isGzipFile reports whether f starts with the gzip magic bytes, without
+// moving its read offset
+func isGzipFile(f *os.File) bool {
+	magic := make([]byte, len(gzipMagic))
+	n, _ := f.ReadAt(magic, 0)
+	return n == len(magic) && string(magic) == gzipMagic
+}
+
+// handleLine parses a single raw line and forwards it to the analyzer,
+// first joining continuation lines in multiline mode. It returns false if
+// the reader has been stopped.
+func (r *Reader) handleLine(logText string) bool {
+	if r.multiline {
+		record, ready := r.joinLine(logText)
+		if !ready {
+			return true
+		}
+		logText = record
+	}
+	return r.handleRecord(logText)
+}
+
+// handleRecord parses a complete record and forwards it to the analyzer.
+// It returns false if the reader has been stopped.
+func (r *Reader) handleRecord(logText string) bool {
+	if r.jobs != nil {
+		return r.dispatchLine(rawLine{text: logText})
+	}
+
+	select {
+	case <-r.stopChan:
+		return false
+	default:
+		return r.emit(r.parseLine(logText))
+	}
+}
+
+// emit forwards a parsed entry to the analyzer. It returns false if the
+// reader was stopped, which also interrupts a push blocked on a full queue.
+func (r *Reader) emit(entry models.LogEntry) bool {
+	if !entry.IsValid {
+		if r.debugMode {
+			r.debugLogger.Printf("Skipped malformed entry (%s): %s", entry.Reject, entry.OriginalLog)
+		}
+		if r.rejects != nil {
+			r.rejects.write(entry.Reject, entry.OriginalLog)
+		}
+	}
+	if entry.IsValid && r.filtered(entry) {
+		// Keep only what is needed to count it, the analyzer skips the rest
+		entry = models.LogEntry{IsValid: true, Filtered: true, Timestamp: entry.Timestamp, Level: entry.Level}
+	}
+	if r.replaySpeed > 0 {
+		r.pace(&entry)
 	}
+
+	return r.queue.Push(entry, r.stopChan)
 }
 
-func (r *Reader) parseLine(line string) models.LogEntry {
+// parseText is the default parser, matching lines against the pattern
+// set with SetPattern
+func (r *Reader) parseText(line string) (models.LogEntry, error) {
+	line, continuation := r.splitContinuation(line)
+
+	matches := r.pattern.FindStringSubmatch(line)
+	if matches == nil {
+		return models.LogEntry{}, Reject(models.RejectRegexMiss)
+	}
+
+	timestamp, ok := r.parseTimestamp(r.field(matches, FieldTimestamp))
+	if !ok {
+		return models.LogEntry{}, Reject(models.RejectBadTimestamp)
+	}
+
 	entry := models.LogEntry{
-		OriginalLog: line,
-		IsValid:     false,
+		Timestamp: timestamp,
+		Level:     r.field(matches, FieldLevel),
+		IP:        normalizeIP(r.field(matches, FieldIP)),
+	}
+	for name, group := range r.extraFields {
+		if value := matches[group]; value != "" {
+			if entry.Fields == nil {
+				entry.Fields = make(map[string]string, len(r.extraFields))
+			}
+			entry.Fields[name] = value
+		}
 	}
 
-	// Handle empty lines and completely malformed entries gracefully
-	if line == "" {
-		return entry
+	message := r.field(matches, FieldMessage)
+	if continuation != "" {
+		message += "\n" + continuation
 	}
+	r.setMessage(&entry, message)
+
+	return entry, nil
+}
 
-	matches := logRegex.FindStringSubmatch(line)
-	if matches == nil || len(matches) < 4 {
-		return entry
+// parseTimestamp tries each configured layout in turn
+func (r *Reader) parseTimestamp(value string) (time.Time, bool) {
+	loc := r.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range r.timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
 	}
 
-	// Parse timestamp
-	timestamp, err := time.Parse("2006-01-02T15:04:05Z", matches[1])
-	if err != nil {
-		return entry
+	if r.debugMode {
+		r.debugLogger.Printf("No time layout matched timestamp %q", value)
 	}
+	return time.Time{}, false
+}
 
-	entry.Timestamp = timestamp
-	entry.Level = matches[2]
-	entry.IP = matches[3]
-	entry.IsValid = true
+// setMessage records the message of ERROR and critical entries and
+// extracts the error type of ERROR entries, as well as the request
+// duration of any entry
+func (r *Reader) setMessage(entry *models.LogEntry, message string) {
+	if r.durationRe != nil && message != "" {
+		if durationMatches := r.durationRe.FindStringSubmatch(message); durationMatches != nil {
+			if ms, err := strconv.ParseFloat(durationMatches[1], 64); err == nil {
+				entry.DurationMs = ms
+				entry.HasDuration = true
+			}
+		}
+	}
 
-	// Parse error message if present
-	if entry.Level == "ERROR" && len(matches) > 4 && matches[4] != "" {
-		entry.Message = matches[4]
-		errorMatches := errorRegex.FindStringSubmatch(matches[4])
-		if errorMatches != nil && len(errorMatches) > 1 {
-			entry.ErrorType = errorMatches[1]
+	if models.LevelSeverity(entry.Level) == models.SeverityCritical {
+		entry.Message = message
+	}
+
+	if entry.Level == "ERROR" && message != "" {
+		entry.Message = message
+		entry.ErrorType = r.fingerprintOf(r.errorType(message))
+	}
+}
+
+// errorType returns the error type from the first error pattern matching message
+func (r *Reader) errorType(message string) string {
+	for _, re := range r.errorRegexes {
+		errorMatches := re.FindStringSubmatch(message)
+		if errorMatches == nil {
+			continue
+		}
+		if len(errorMatches) > 1 {
+			return errorMatches[1]
 		}
+		return errorMatches[0]
 	}
+	return ""
+}
+
+// normalizeIP strips brackets and ports from an address and returns its
+// canonical form, so the same IPv4 or IPv6 client always aggregates under
+// one key (e.g. "[2001:db8:0::1]:443" becomes "2001:db8::1"). Values that
+// are not addresses are returned unchanged.
+func normalizeIP(value string) string {
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	// Unmap so IPv4-mapped IPv6 addresses (::ffff:1.2.3.4) match plain IPv4
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return value
+}
 
-	return entry
+// levelSet converts a list of levels into an upper-case lookup set
+func levelSet(levels []string) map[string]bool {
+	set := make(map[string]bool, len(levels))
+	for _, level := range levels {
+		set[strings.ToUpper(level)] = true
+	}
+	return set
+}
+
+// field returns the submatch mapped to the given field, or "" if it is unmapped
+func (r *Reader) field(matches []string, name string) string {
+	group, ok := r.fieldMap[name]
+	if !ok || group >= len(matches) {
+		return ""
+	}
+	return matches[group]
 }
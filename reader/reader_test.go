@@ -0,0 +1,266 @@
+package reader
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"log_analyzer/queue"
+)
+
+// newTestReader returns a reader with no input, for parsing lines directly
+func newTestReader() *Reader {
+	return newReader(nil, queue.New(16, queue.PolicyBlock), false)
+}
+
+func TestParseTimestampFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp string
+		want      time.Time
+	}{
+		{"UTC", "2024-03-01T12:00:00Z", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"positive offset", "2024-03-01T14:00:00+02:00", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"negative offset", "2024-03-01T07:30:00-04:30", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"milliseconds", "2024-03-01T12:00:00.123Z", time.Date(2024, 3, 1, 12, 0, 0, 123e6, time.UTC)},
+		{"milliseconds with offset", "2024-03-01T13:00:00.250+01:00", time.Date(2024, 3, 1, 12, 0, 0, 250e6, time.UTC)},
+		{"nanoseconds", "2024-03-01T12:00:00.123456789Z", time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)},
+	}
+
+	r := newTestReader()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := r.parseLine("[" + tt.timestamp + "] INFO - IP:10.0.0.1 request served")
+			if !entry.IsValid {
+				t.Fatalf("line rejected as %s", entry.Reject)
+			}
+			if !entry.Timestamp.Equal(tt.want) {
+				t.Errorf("timestamp = %s, want %s", entry.Timestamp, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampKeepsOffset(t *testing.T) {
+	r := newTestReader()
+	entry := r.parseLine("[2024-03-01T14:00:00.500+02:00] INFO - IP:10.0.0.1 request served")
+
+	if _, offset := entry.Timestamp.Zone(); offset != 2*60*60 {
+		t.Errorf("offset = %ds, want +02:00", offset)
+	}
+}
+
+func TestParseTimestampCustomLayouts(t *testing.T) {
+	r := newTestReader()
+	r.SetTimeLayouts([]string{"2006-01-02 15:04:05.000", "02/Jan/2006:15:04:05 -0700"})
+
+	tests := []struct {
+		timestamp string
+		want      time.Time
+	}{
+		{"2024-03-01 12:00:00.042", time.Date(2024, 3, 1, 12, 0, 0, 42e6, time.UTC)},
+		{"01/Mar/2024:13:00:00 +0100", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok := r.parseTimestamp(tt.timestamp)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%q) = %s, %v, want %s", tt.timestamp, got, ok, tt.want)
+		}
+	}
+
+	// The defaults no longer apply
+	if _, ok := r.parseTimestamp("2024-03-01T12:00:00Z"); ok {
+		t.Error("RFC 3339 timestamp parsed without its layout")
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"10.0.0.1", "10.0.0.1"},
+		{"10.0.0.1:8080", "10.0.0.1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"2001:DB8:0:0::1", "2001:db8::1"},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8:0::1]:443", "2001:db8::1"},
+		{"::1", "::1"},
+		{"::ffff:192.0.2.7", "192.0.2.7"},
+		{"::FFFF:c000:0207", "192.0.2.7"},
+		{"[::ffff:192.0.2.7]:8080", "192.0.2.7"},
+		{"not-an-ip", "not-an-ip"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeIP(tt.value); got != tt.want {
+			t.Errorf("normalizeIP(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseIPv6Lines(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"fe80::a:b:c:d", "fe80::a:b:c:d"},
+		{"::ffff:192.0.2.7", "192.0.2.7"},
+		{"[::ffff:192.0.2.7]:8080", "192.0.2.7"},
+	}
+
+	r := newTestReader()
+	for _, tt := range tests {
+		entry := r.parseLine("[2024-03-01T12:00:00Z] ERROR - IP:" + tt.ip + " Error 500 - Timeout")
+		if !entry.IsValid {
+			t.Errorf("line with IP %s rejected as %s", tt.ip, entry.Reject)
+			continue
+		}
+		if entry.IP != tt.want {
+			t.Errorf("IP %s parsed as %q, want %q", tt.ip, entry.IP, tt.want)
+		}
+		if entry.ErrorType != "Timeout" {
+			t.Errorf("IP %s: error type %q, want Timeout", tt.ip, entry.ErrorType)
+		}
+	}
+}
+
+func TestLineFiltersKeepLinesOutOfQueue(t *testing.T) {
+	input := strings.Join([]string{
+		"[2024-03-01T12:00:00Z] INFO - IP:10.0.0.1 GET /api/v2/orders",
+		"[2024-03-01T12:00:01Z] INFO - IP:10.0.0.2 GET /api/v1/orders",
+		"[2024-03-01T12:00:02Z] ERROR - IP:10.0.0.3 Error 500 - Timeout on /api/v2/orders",
+		"[2024-03-01T12:00:03Z] ERROR - IP:10.0.0.4 Error 500 - Timeout on /api/v1/orders",
+		"[2024-03-01T12:00:04Z] INFO - IP:10.0.0.5 GET /api/v2/health",
+		"[2024-03-01T12:00:05Z] DEBUG - IP:10.0.0.6 GET /api/v2/orders",
+	}, "\n") + "\n"
+
+	tests := []struct {
+		name     string
+		minLevel string
+		want     []string // IPs of the entries passed on for analysis
+	}{
+		{"line filters", "", []string{"10.0.0.1", "10.0.0.3", "10.0.0.6"}},
+		{"with the level filter", "ERROR", []string{"10.0.0.3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := queue.New(16, queue.PolicyBlock)
+			r := NewStreamReader(strings.NewReader(input), entries, false)
+			r.SetLevels([]string{"ERROR", "INFO", "DEBUG"})
+			r.SetLineFilters(regexp.MustCompile(`/api/v2/`), regexp.MustCompile(`health`))
+			if tt.minLevel != "" {
+				if err := r.SetMinLevel(tt.minLevel); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r.Start()
+			<-r.Done()
+
+			var passed []string
+			filtered := 0
+			for {
+				entry, ok := entries.TryPop()
+				if !ok {
+					break
+				}
+				if !entry.IsValid {
+					t.Fatalf("line rejected as %s: %s", entry.Reject, entry.OriginalLog)
+				}
+				if entry.Filtered {
+					// Only a marker to count is queued, none of the line
+					if entry.OriginalLog != "" || entry.Message != "" || entry.IP != "" {
+						t.Errorf("filtered line queued with its content: %+v", entry)
+					}
+					filtered++
+					continue
+				}
+				passed = append(passed, entry.IP)
+			}
+
+			if !reflect.DeepEqual(passed, tt.want) {
+				t.Errorf("lines passed on from %v, want %v", passed, tt.want)
+			}
+			if want := 6 - len(tt.want); filtered != want {
+				t.Errorf("%d filtered markers, want %d", filtered, want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampInLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	r := newTestReader()
+	r.SetTimeLayouts([]string{"2006-01-02 15:04:05", time.RFC3339})
+	r.SetLocation(berlin)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		want      time.Time
+	}{
+		{"winter time", "2024-01-15 12:00:00", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"summer time", "2024-07-15 12:00:00", time.Date(2024, 7, 15, 10, 0, 0, 0, time.UTC)},
+		{"before spring forward", "2024-03-31 01:59:59", time.Date(2024, 3, 31, 0, 59, 59, 0, time.UTC)},
+		{"after spring forward", "2024-03-31 03:00:00", time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC)},
+		{"before fall back", "2024-10-27 01:59:59", time.Date(2024, 10, 26, 23, 59, 59, 0, time.UTC)},
+		{"after fall back", "2024-10-27 03:00:00", time.Date(2024, 10, 27, 2, 0, 0, 0, time.UTC)},
+		{"Z is still UTC", "2024-07-15T12:00:00Z", time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)},
+		{"offset is kept", "2024-07-15T12:00:00-05:00", time.Date(2024, 7, 15, 17, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := r.parseTimestamp(tt.timestamp)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp(%q) = %s, %v, want %s", tt.timestamp, got.UTC(), ok, tt.want)
+			}
+		})
+	}
+
+	// The hour skipped by spring forward leaves no gap between the two
+	// readings either side of it
+	before, _ := r.parseTimestamp("2024-03-31 01:59:59")
+	after, _ := r.parseTimestamp("2024-03-31 03:00:00")
+	if gap := after.Sub(before); gap != time.Second {
+		t.Errorf("spring forward: %s between 01:59:59 and 03:00:00, want 1s", gap)
+	}
+}
+
+func TestParseTimestampDefaultsToUTC(t *testing.T) {
+	r := newTestReader()
+	r.SetTimeLayouts([]string{"2006-01-02 15:04:05"})
+
+	got, ok := r.parseTimestamp("2024-07-15 12:00:00")
+	if want := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC); !ok || !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("parseTimestamp = %s, %v, want %s", got, ok, want)
+	}
+}
+
+func TestStopWhileQueueFull(t *testing.T) {
+	entries := queue.New(1, queue.PolicyBlock)
+	r := NewStreamReader(strings.NewReader(benchmarkInput(10)), entries, false)
+	r.Start()
+
+	// Nothing consumes, so the reader blocks pushing the second entry
+	time.Sleep(50 * time.Millisecond)
+	r.Stop()
+
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader did not finish after a stop while the queue was full")
+	}
+	if got := entries.Len(); got != 1 {
+		t.Errorf("queue holds %d entries, want 1", got)
+	}
+}
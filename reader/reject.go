@@ -0,0 +1,89 @@
+// reader/reject.go - Writes rejected raw lines to a file for debugging parser settings.
+
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// rejectFlushInterval is how often buffered rejected lines are flushed
+const rejectFlushInterval = time.Second
+
+// rejectFile buffers rejected lines, each prefixed with its reject reason
+type rejectFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	closed bool
+	mux    sync.Mutex
+}
+
+// SetRejectFile appends every line that fails to parse to the file at path,
+// as "<reason>\t<raw line>". Writes are buffered and flushed every second
+// and when the reader is stopped.
+func (r *Reader) SetRejectFile(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	r.rejects = &rejectFile{file: f, writer: bufio.NewWriter(f)}
+	return nil
+}
+
+// write records one rejected line
+func (rf *rejectFile) write(reason, line string) {
+	rf.mux.Lock()
+	defer rf.mux.Unlock()
+
+	// Lines still in flight when the reader stops are dropped
+	if rf.closed {
+		return
+	}
+	fmt.Fprintf(rf.writer, "%s\t%s\n", reason, line)
+}
+
+// flush writes out buffered lines, closing the file when final is set
+func (rf *rejectFile) flush(final bool) error {
+	rf.mux.Lock()
+	defer rf.mux.Unlock()
+
+	if rf.closed {
+		return nil
+	}
+	err := rf.writer.Flush()
+	if final {
+		rf.closed = true
+		if closeErr := rf.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// closeRejects flushes and closes the reject file
+func (r *Reader) closeRejects() {
+	if err := r.rejects.flush(true); err != nil && r.debugMode {
+		r.debugLogger.Printf("Failed to close reject file: %v", err)
+	}
+}
+
+// flushRejects periodically flushes the reject file until the reader stops
+func (r *Reader) flushRejects() {
+	ticker := time.NewTicker(rejectFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return // Stop flushes and closes the file itself
+		case <-ticker.C:
+			if err := r.rejects.flush(false); err != nil && r.debugMode {
+				r.debugLogger.Printf("Failed to flush reject file: %v", err)
+			}
+		}
+	}
+}
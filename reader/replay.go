@@ -0,0 +1,48 @@
+// reader/replay.go - Replays historical logs at the pace they were written.
+
+package reader
+
+import (
+	"fmt"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// SetReplay paces entries by the gaps between their timestamps, divided by
+// speed (2 replays twice as fast). Replayed entries are re-stamped with the
+// time they are emitted, so the sliding window treats them as live traffic.
+func (r *Reader) SetReplay(speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("replay speed must be positive, got %g", speed)
+	}
+
+	r.replaySpeed = speed
+	return nil
+}
+
+// pace waits until entry is due and rebases its timestamp to now. Invalid
+// entries pass straight through, and timestamps that are equal to or earlier
+// than the previous one are emitted without waiting.
+func (r *Reader) pace(entry *models.LogEntry) {
+	if !entry.IsValid {
+		return
+	}
+
+	if !r.replayLast.IsZero() {
+		if gap := entry.Timestamp.Sub(r.replayLast); gap > 0 {
+			timer := time.NewTimer(time.Duration(float64(gap) / r.replaySpeed))
+			select {
+			case <-timer.C:
+			case <-r.stopChan:
+				timer.Stop()
+			}
+		}
+	}
+
+	// Only move forward, so one out-of-order entry does not delay the rest
+	if entry.Timestamp.After(r.replayLast) {
+		r.replayLast = entry.Timestamp
+	}
+	entry.Timestamp = time.Now()
+}
@@ -0,0 +1,216 @@
+// reader/syslog.go - Parsing of syslog lines in the RFC 3164 (BSD) and RFC 5424 formats.
+
+package reader
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// SyslogFormat selects which syslog format lines are parsed as
+type SyslogFormat string
+
+const (
+	// SyslogAuto detects the format of each line by its version field
+	SyslogAuto SyslogFormat = "syslog"
+	// SyslogRFC3164 parses BSD syslog lines, e.g.
+	// "<34>Oct 11 22:14:15 host su: 'su root' failed"
+	SyslogRFC3164 SyslogFormat = "syslog-rfc3164"
+	// SyslogRFC5424 parses lines such as
+	// "<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 - message"
+	SyslogRFC5424 SyslogFormat = "syslog-rfc5424"
+)
+
+// syslogLevels maps syslog severities (0-7) to level names
+var syslogLevels = [8]string{
+	"CRITICAL", // Emergency
+	"CRITICAL", // Alert
+	"CRITICAL", // Critical
+	"ERROR",
+	"WARN",
+	"INFO", // Notice
+	"INFO",
+	"DEBUG",
+}
+
+// SyslogLevels are the levels syslog severities map to, in display order.
+// They are accepted in syslog mode unless other levels are set.
+var SyslogLevels = []string{"CRITICAL", "ERROR", "WARN", "INFO", "DEBUG"}
+
+// ParseSyslogFormat reports whether name selects a syslog format
+func ParseSyslogFormat(name string) (SyslogFormat, bool) {
+	switch format := SyslogFormat(name); format {
+	case SyslogAuto, SyslogRFC3164, SyslogRFC5424:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+// SetSyslogMode switches the reader to parsing syslog lines. The severity
+// in the priority field becomes the level (emergency, alert and critical
+// map to CRITICAL, warning to WARN, notice to INFO), and the host becomes
+// the entry's IP. If the accepted levels are still the defaults, they are
+// widened to SyslogLevels, as the defaults would reject some severities;
+// levels set with SetLevels are kept.
+func (r *Reader) SetSyslogMode(format SyslogFormat) {
+	r.syslogFormat = format
+	r.parser = ParserFunc(r.parseSyslog)
+	if maps.Equal(r.levels, levelSet(DefaultLevels)) {
+		r.levels = levelSet(SyslogLevels)
+	}
+}
+
+// parseSyslog is the parser of syslog lines. A missing or invalid priority
+// rejects the line.
+func (r *Reader) parseSyslog(line string) (models.LogEntry, error) {
+	severity, rest, ok := syslogPriority(line)
+	if !ok {
+		return models.LogEntry{}, Reject(models.RejectBadSyslog)
+	}
+
+	format := r.syslogFormat
+	if format == SyslogAuto {
+		format = SyslogRFC3164
+		if strings.HasPrefix(rest, "1 ") {
+			format = SyslogRFC5424
+		}
+	}
+
+	var timestamp time.Time
+	var host, message string
+	if format == SyslogRFC5424 {
+		timestamp, host, message, ok = parseRFC5424(rest)
+	} else {
+		loc := r.location
+		if loc == nil {
+			loc = time.Local
+		}
+		timestamp, host, message, ok = parseRFC3164(rest, time.Now(), loc)
+	}
+	if !ok {
+		return models.LogEntry{}, Reject(models.RejectBadTimestamp)
+	}
+
+	entry := models.LogEntry{
+		Timestamp: timestamp,
+		Level:     syslogLevels[severity],
+		IP:        normalizeIP(host),
+	}
+	r.setMessage(&entry, message)
+
+	return entry, nil
+}
+
+// syslogPriority parses the leading "<PRI>" of line, returning the
+// severity it encodes and the rest of the line
+func syslogPriority(line string) (int, string, bool) {
+	if !strings.HasPrefix(line, "<") {
+		return 0, "", false
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", false
+	}
+	priority, err := strconv.Atoi(line[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, "", false
+	}
+	return priority % 8, line[end+1:], true
+}
+
+// parseRFC3164 parses "Mmm dd hh:mm:ss HOSTNAME MSG". The timestamp has no
+// year or zone: it is read in loc in the year that puts it closest before
+// now.
+func parseRFC3164(rest string, now time.Time, loc *time.Location) (time.Time, string, string, bool) {
+	if len(rest) < len(time.Stamp) {
+		return time.Time{}, "", "", false
+	}
+	timestamp, err := time.ParseInLocation(time.Stamp, rest[:len(time.Stamp)], loc)
+	if err != nil {
+		return time.Time{}, "", "", false
+	}
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		// December logs read in January
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+
+	host, message, _ := strings.Cut(strings.TrimPrefix(rest[len(time.Stamp):], " "), " ")
+	return timestamp, host, message, true
+}
+
+// parseRFC5424 parses "1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+// STRUCTURED-DATA [MSG]", keeping the app name in front of the message
+func parseRFC5424(rest string) (time.Time, string, string, bool) {
+	fields := strings.SplitN(strings.TrimPrefix(rest, "1 "), " ", 6)
+	if len(fields) < 6 {
+		return time.Time{}, "", "", false
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return time.Time{}, "", "", false
+	}
+
+	host := nilValue(fields[1])
+	message, ok := skipStructuredData(fields[5])
+	if !ok {
+		return time.Time{}, "", "", false
+	}
+	message = strings.TrimPrefix(message, "\ufeff") // UTF-8 byte order mark
+	if app := nilValue(fields[2]); app != "" {
+		message = app + ": " + message
+	}
+	return timestamp, host, message, true
+}
+
+// nilValue returns field, or "" for the syslog nil value "-"
+func nilValue(field string) string {
+	if field == "-" {
+		return ""
+	}
+	return field
+}
+
+// skipStructuredData skips the structured data at the start of s, either
+// "-" or one or more "[id key="value" ...]" elements, returning the message
+// after it
+func skipStructuredData(s string) (string, bool) {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " "), true
+	}
+
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		end := elementEnd(s, i)
+		if end < 0 {
+			return "", false
+		}
+		i = end + 1
+	}
+	if i == 0 {
+		return "", false
+	}
+	return strings.TrimPrefix(s[i:], " "), true
+}
+
+// elementEnd returns the index of the "]" closing the structured data
+// element starting at start, skipping quoted and escaped characters, or -1
+func elementEnd(s string, start int) int {
+	inQuotes := false
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++ // Skip the escaped character
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == ']' && !inQuotes:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,24 @@
+package reader
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSyslogSeveritiesAcceptedByDefault(t *testing.T) {
+	r := newTestReader()
+	r.SetSyslogMode(SyslogAuto)
+
+	for severity, want := range syslogLevels {
+		// Facility user (1), so the priority is 8 plus the severity
+		line := fmt.Sprintf("<%d>1 2024-03-01T12:00:00Z 10.0.0.1 app - - - request served", 8+severity)
+		entry := r.parseLine(line)
+		if !entry.IsValid {
+			t.Errorf("severity %d rejected as %s", severity, entry.Reject)
+			continue
+		}
+		if entry.Level != want {
+			t.Errorf("severity %d parsed as %s, want %s", severity, entry.Level, want)
+		}
+	}
+}
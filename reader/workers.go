@@ -0,0 +1,115 @@
+// reader/workers.go - Optional pool of parser goroutines for multi-core parsing.
+
+package reader
+
+import (
+	"sync"
+
+	"log_analyzer/models"
+)
+
+// parserQueueSize is the number of lines buffered per parser worker
+const parserQueueSize = 256
+
+// rawLine is a line waiting to be parsed, tagged with its position in the input
+type rawLine struct {
+	seq     uint64
+	text    string
+	tooLong bool // text is only the start of an oversized line, see skipLongLine
+}
+
+// parsedLine is the result of parsing a rawLine
+type parsedLine struct {
+	seq   uint64
+	entry models.LogEntry
+}
+
+// SetParserWorkers sets the number of goroutines parsing lines in parallel.
+// Entries are still forwarded in input order, since the sliding window
+// relies on entries arriving sorted by time. Values below 2 parse inline.
+func (r *Reader) SetParserWorkers(n int) {
+	r.parserWorkers = n
+}
+
+// startParserPool starts the parser workers and the goroutine that puts
+// their results back into input order
+func (r *Reader) startParserPool() {
+	r.jobs = make(chan rawLine, r.parserWorkers*parserQueueSize)
+	results := make(chan parsedLine, r.parserWorkers*parserQueueSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.parserWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range r.jobs {
+				results <- parsedLine{seq: line.seq, entry: r.parseRaw(line)}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go r.reorder(results)
+}
+
+// parseRaw parses a line taken from the parser pool's queue
+func (r *Reader) parseRaw(line rawLine) models.LogEntry {
+	if line.tooLong {
+		return longLineEntry(line.text)
+	}
+	return r.parseLine(line.text)
+}
+
+// dispatchLine hands a line to the parser pool, numbering it. It returns
+// false if the reader has been stopped.
+func (r *Reader) dispatchLine(line rawLine) bool {
+	line.seq = r.nextSeq
+	select {
+	case <-r.stopChan:
+		return false
+	case r.jobs <- line:
+		r.nextSeq++
+		return true
+	}
+}
+
+// reorder forwards parsed entries in sequence order, holding back results
+// that finished ahead of earlier lines
+func (r *Reader) reorder(results chan parsedLine) {
+	defer close(r.doneChan)
+
+	pending := make(map[uint64]models.LogEntry)
+	var next uint64
+	stopped := false
+
+	for result := range results {
+		// Keep draining after a stop so the workers can exit
+		if stopped {
+			continue
+		}
+		select {
+		case <-r.stopChan:
+			stopped = true
+			continue
+		default:
+		}
+
+		pending[result.seq] = result.entry
+		for {
+			entry, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !r.emit(entry) {
+				stopped = true
+				break
+			}
+		}
+	}
+}
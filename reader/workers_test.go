@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"log_analyzer/queue"
+)
+
+// benchmarkInput returns n log lines of mixed levels, one per millisecond
+func benchmarkInput(n int) string {
+	levels := []string{"INFO", "DEBUG", "ERROR"}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		level := levels[i%len(levels)]
+		fmt.Fprintf(&b, "[%s] %s - IP:[2001:db8::%x]:443 ",
+			start.Add(time.Duration(i)*time.Millisecond).Format(time.RFC3339Nano), level, i%4096)
+		if level == "ERROR" {
+			fmt.Fprintf(&b, "Error 500 - Timeout after %dms on /api/v1/orders/%d\n", i%900, i)
+		} else {
+			fmt.Fprintf(&b, "GET /api/v1/orders/%d took %dms\n", i, i%900)
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkParserWorkers(b *testing.B) {
+	const lines = 20000
+	input := benchmarkInput(lines)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				entries := queue.New(lines, queue.PolicyBlock)
+				r := NewStreamReader(strings.NewReader(input), entries, false)
+				r.SetParserWorkers(workers)
+				b.StartTimer()
+
+				r.Start()
+				<-r.Done()
+
+				b.StopTimer()
+				if got := entries.Len(); got != lines {
+					b.Fatalf("queued %d entries, want %d", got, lines)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestParserWorkersKeepInputOrder(t *testing.T) {
+	const lines = 5000
+	entries := queue.New(lines, queue.PolicyBlock)
+	r := NewStreamReader(strings.NewReader(benchmarkInput(lines)), entries, false)
+	r.SetParserWorkers(4)
+	r.Start()
+	<-r.Done()
+
+	var last time.Time
+	for i := 0; i < lines; i++ {
+		entry, ok := entries.TryPop()
+		if !ok {
+			t.Fatalf("queued %d entries, want %d", i, lines)
+		}
+		if !entry.IsValid {
+			t.Fatalf("line %d rejected as %s", i, entry.Reject)
+		}
+		if !entry.Timestamp.After(last) {
+			t.Fatalf("line %d at %s forwarded after %s", i, entry.Timestamp, last)
+		}
+		last = entry.Timestamp
+	}
+}
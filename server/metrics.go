@@ -0,0 +1,149 @@
+// server/metrics.go - Prometheus text-format exporter for the analyzer stats.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"log_analyzer/models"
+)
+
+// counter turns a cumulative value that may be reset upstream into a
+// monotonic Prometheus counter
+type counter struct {
+	total float64
+	last  float64
+}
+
+// observe folds the latest upstream value into the counter
+func (c *counter) observe(value float64) {
+	if value >= c.last {
+		c.total += value - c.last
+	} else {
+		// Upstream was reset; everything since the reset is new
+		c.total += value
+	}
+	c.last = value
+}
+
+// Metrics tracks the counters exported on /metrics across scrapes. They
+// must observe every published snapshot, see Server.HandleStats, so a
+// stats reset between two scrapes does not lose what was counted before it.
+type Metrics struct {
+	processed counter
+	skipped   counter
+	filtered  counter
+	dropped   counter
+	mux       sync.Mutex
+}
+
+// Observe updates the counters from a stats snapshot
+func (m *Metrics) Observe(stats *models.LogStats) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.processed.observe(float64(stats.EntriesProcessed))
+	m.skipped.observe(float64(stats.SkippedEntries))
+	m.filtered.observe(float64(stats.FilteredEntries))
+	m.dropped.observe(float64(stats.DroppedEntries))
+}
+
+// Write renders stats, together with the monotonic counters, in the
+// Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer, stats *models.LogStats) {
+	m.mux.Lock()
+	processed := m.processed.total
+	skipped := m.skipped.total
+	filtered := m.filtered.total
+	dropped := m.dropped.total
+	m.mux.Unlock()
+
+	writeMetric(w, "log_entries_processed_total", "counter", "Valid log entries processed since start.", processed)
+	writeMetric(w, "log_entries_skipped_total", "counter", "Malformed log entries skipped since start.", skipped)
+	writeMetric(w, "log_entries_dropped_total", "counter", "Log entries dropped because the analyzer could not keep up.", dropped)
+	writeMetric(w, "log_entries_filtered_total", "counter", "Valid log entries rejected by the level or line filters since start.", filtered)
+	writeMetric(w, "log_current_rate", "gauge", "Current processing rate in entries per second.", stats.CurrentRate)
+	writeMetric(w, "log_smoothed_rate", "gauge", "Exponentially weighted moving average of the processing rate.", stats.SmoothedRate)
+	writeMetric(w, "log_peak_rate", "gauge", "Peak processing rate in entries per second.", stats.PeakRate)
+	writeMetric(w, "log_window_seconds", "gauge", "Current sliding window size in seconds.", float64(stats.WindowSize))
+	writeMetric(w, "log_buffer_entries", "gauge", "Entries waiting in the queue between reader and analyzer.", float64(stats.BufferUsed))
+	writeMetric(w, "log_buffer_capacity", "gauge", "Capacity of the queue between reader and analyzer.", float64(stats.BufferSize))
+	writeMetric(w, "log_unique_ips", "gauge", "Distinct source IPs in the sliding window.", float64(stats.UniqueIPs))
+	writeMetric(w, "log_unique_ips_lifetime", "gauge", "Estimated distinct source IPs since start.", float64(stats.Lifetime.UniqueIPs))
+
+	if stats.LatencySamples > 0 {
+		writeLabeled(w, "log_latency_ms", "gauge", "Request duration percentiles over the sliding window.", "quantile", map[string]float64{
+			"0.5":  stats.LatencyP50,
+			"0.95": stats.LatencyP95,
+			"0.99": stats.LatencyP99,
+		})
+	}
+
+	writeLabeled(w, "log_skipped_entries", "gauge", "Malformed entries skipped since start, by reason.", "reason", intValues(stats.RejectCounts))
+	writeLabeled(w, "log_level_entries", "gauge", "Entries per log level in the sliding window.", "level", intValues(stats.LevelCounts))
+	writeLabeled(w, "log_level_rate", "gauge", "Entries per second by log level over the sliding window.", "level", stats.LevelRates)
+	writeLabeled(w, "log_error_type_entries", "gauge", "Entries per error type in the sliding window.", "error_type", intValues(stats.ErrorCounts))
+	writeLabeled(w, "log_error_rate", "gauge", "Errors per second by error type.", "error_type", stats.ErrorRates)
+	writeLabeled(w, "log_emerging_pattern_change_percent", "gauge", "Percentage increase of emerging error patterns.", "error_type", stats.EmergingPatterns)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The counters are only updated by HandleStats: the snapshot may be
+	// newer than the last stats delivered, which would read as a reset
+	stats := s.source.Snapshot()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.Write(w, stats)
+}
+
+// HandleStats implements notify.Sink, updating the counters from every
+// stats update rather than only on scrape
+func (s *Server) HandleStats(stats *models.LogStats) {
+	s.metrics.Observe(stats)
+}
+
+// HandleAlert implements notify.Sink; alerts are not exported
+func (s *Server) HandleAlert(alert models.Alert) {}
+
+func writeMetric(w io.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+func writeLabeled(w io.Writer, name, kind, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+
+	// Sort label values for stable output
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %g\n", name, label, escapeLabel(k), values[k])
+	}
+}
+
+func intValues(m map[string]int) map[string]float64 {
+	result := make(map[string]float64, len(m))
+	for k, v := range m {
+		result[k] = float64(v)
+	}
+	return result
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
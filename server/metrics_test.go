@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"log_analyzer/models"
+)
+
+// staticSource serves a fixed snapshot
+type staticSource struct {
+	stats *models.LogStats
+}
+
+func (s staticSource) Snapshot() *models.LogStats {
+	return s.stats
+}
+
+func TestMetricsCountAcrossStatsResets(t *testing.T) {
+	// Published every tick, with a -stats-rotate reset after 300 entries
+	ticks := []int{100, 300, 50, 120}
+
+	latest := models.NewLogStats()
+	s := NewServer("127.0.0.1:0", staticSource{latest})
+	for _, processed := range ticks {
+		stats := models.NewLogStats()
+		stats.EntriesProcessed = processed
+		stats.SkippedEntries = processed / 10
+		s.HandleStats(stats)
+		*latest = *stats
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"log_entries_processed_total 420\n",
+		"log_entries_skipped_total 42\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics lacks %q:\n%s", want, body)
+		}
+	}
+
+	// A scrape does not count the snapshot again
+	rec = httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "log_entries_processed_total 420\n") {
+		t.Errorf("second scrape changed the counter:\n%s", rec.Body.String())
+	}
+}
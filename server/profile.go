@@ -0,0 +1,42 @@
+// server/profile.go - Serves the net/http/pprof profiling endpoints.
+
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// Profiler serves CPU, heap and other runtime profiles under /debug/pprof/,
+// e.g. for `go tool pprof http://localhost:6060/debug/pprof/profile`. It
+// uses its own mux, so the profiles are never exposed on the stats server.
+type Profiler struct {
+	addr       string
+	httpServer *http.Server
+}
+
+// NewProfiler creates a new Profiler listening on addr (e.g. ":6060")
+func NewProfiler(addr string) *Profiler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &Profiler{
+		addr: addr,
+		// No write timeout: a CPU profile or trace streams for its full duration
+		httpServer: &http.Server{Handler: mux},
+	}
+}
+
+// Start binds the listen address and begins serving profiles
+func (p *Profiler) Start() error {
+	return serve(p.httpServer, p.addr)
+}
+
+// Stop shuts the profiler down, waiting briefly for in-flight requests
+func (p *Profiler) Stop() {
+	shutdown(p.httpServer)
+}
@@ -0,0 +1,96 @@
+// server/server.go - Serves the latest analyzer stats over HTTP for scraping,
+// as JSON on /stats and in Prometheus format on /metrics.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"log_analyzer/models"
+)
+
+// shutdownTimeout bounds how long Stop waits for in-flight requests
+const shutdownTimeout = 5 * time.Second
+
+// StatsSource provides race-free snapshots of the current stats
+type StatsSource interface {
+	Snapshot() *models.LogStats
+}
+
+// Server exposes the analyzer's stats over HTTP
+type Server struct {
+	addr       string
+	source     StatsSource
+	metrics    *Metrics
+	httpServer *http.Server
+}
+
+// NewServer creates a new Server listening on addr (e.g. ":8080"). Register
+// it as a stats sink too, so the counters on /metrics see every update.
+func NewServer(addr string, source StatsSource) *Server {
+	s := &Server{
+		addr:    addr,
+		source:  source,
+		metrics: &Metrics{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	s.httpServer = &http.Server{Handler: mux}
+
+	return s
+}
+
+// Start binds the listen address and begins serving requests
+func (s *Server) Start() error {
+	return serve(s.httpServer, s.addr)
+}
+
+// Stop shuts the server down, waiting briefly for in-flight requests
+func (s *Server) Stop() {
+	shutdown(s.httpServer)
+}
+
+// serve binds addr and serves httpServer's requests in the background
+func serve(httpServer *http.Server, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// shutdown stops httpServer, waiting briefly for in-flight requests
+func shutdown(httpServer *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.source.Snapshot()); err != nil {
+		log.Printf("Failed to encode stats: %v", err)
+	}
+}